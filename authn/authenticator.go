@@ -0,0 +1,19 @@
+package authn
+
+import (
+	"errors"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//ErrInvalidCredentials is returned from Authenticator.Authenticate when the
+//user doesn't exist or the password is incorrect; the two aren't
+//distinguished so that clients can't discover which user names exist
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+//Authenticator verifies user names and passwords
+type Authenticator interface {
+	//Authenticate returns the user identified by userName if password
+	//is correct, or ErrInvalidCredentials if it isn't
+	Authenticate(userName string, password string) (*users.User, error)
+}
@@ -0,0 +1,229 @@
+package authn
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davestearns/userservice/models/users"
+	"github.com/go-ldap/ldap/v3"
+)
+
+//ldapTimeout limits each directory connection and search
+const ldapTimeout = 10 * time.Second
+
+//LDAPAttributes names the directory attributes mapped into users.User
+type LDAPAttributes struct {
+	//UserName is the attribute holding the user name,
+	//e.g. "uid", or "sAMAccountName" for Active Directory
+	UserName string
+	//PersonalName is the attribute holding the personal name, e.g. "givenName"
+	PersonalName string
+	//FamilyName is the attribute holding the family name, e.g. "sn"
+	FamilyName string
+	//Email is the attribute holding the email address, e.g. "mail"
+	Email string
+}
+
+//LDAPConfig configures an LDAP Authenticator
+type LDAPConfig struct {
+	//URL is the directory server URL, e.g. "ldaps://ldap.example.com"
+	URL string
+	//StartTLS upgrades ldap:// connections to TLS before binding
+	StartTLS bool
+	//BindDN and BindPassword are the credentials of the service account
+	//used to search for users; both empty means an anonymous search
+	BindDN       string
+	BindPassword string
+	//BaseDN is where the search for users begins
+	BaseDN string
+	//Filter selects the entry of a user, with %s replaced by the
+	//escaped user name, e.g. "(&(objectClass=person)(uid=%s))"
+	Filter string
+	//Attributes are the mapped attributes
+	Attributes LDAPAttributes
+}
+
+//LDAP is an Authenticator that verifies passwords by binding to a directory
+//as the user. Users are provisioned into a users.Store when they first sign
+//in, and their profiles are updated from the directory on each sign-in.
+//User names are case-insensitive in most directories, so they're lowercased.
+//Accounts that weren't provisioned from the directory are never signed in,
+//so that a directory user can't take over a local account with the same name.
+type LDAP struct {
+	config *LDAPConfig
+	store  users.Store
+}
+
+//NewLDAP constructs a new LDAP Authenticator
+func NewLDAP(config *LDAPConfig, store users.Store) (*LDAP, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %v", err)
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("LDAP URL scheme must be ldap or ldaps")
+	}
+	if strings.Count(config.Filter, "%s") != 1 {
+		return nil, fmt.Errorf("LDAP filter must contain a single %%s for the user name")
+	}
+	if len(config.Attributes.UserName) == 0 {
+		return nil, fmt.Errorf("LDAP user name attribute must be supplied")
+	}
+	return &LDAP{config: config, store: store}, nil
+}
+
+//Authenticate implements the Authenticator interface
+func (l *LDAP) Authenticate(userName string, password string) (*users.User, error) {
+	//a bind with an empty password is an unauthenticated
+	//bind, which many servers report as successful
+	if len(userName) == 0 || len(password) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := l.find(conn, userName)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("error binding to LDAP as user: %v", err)
+	}
+	profile := l.config.Attributes.toUser(entry)
+	if len(profile.UserName) == 0 {
+		profile.UserName = userName
+	}
+	profile.UserName = strings.ToLower(profile.UserName)
+	return provision(l.store, profile)
+}
+
+//dial connects to the directory, binding as the service account if configured
+func (l *LDAP) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(l.config.URL, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to LDAP: %v", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+	if l.config.StartTLS {
+		u, _ := url.Parse(l.config.URL)
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error starting TLS with LDAP: %v", err)
+		}
+	}
+	if len(l.config.BindDN) > 0 {
+		if err := conn.Bind(l.config.BindDN, l.config.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error binding to LDAP as service account: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+//find returns the single directory entry for userName,
+//or ErrInvalidCredentials if there isn't exactly one
+func (l *LDAP) find(conn *ldap.Conn, userName string) (*ldap.Entry, error) {
+	attrs := l.config.Attributes
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf(l.config.Filter, ldap.EscapeFilter(userName)),
+		attrs.names(), nil))
+	if err != nil {
+		return nil, fmt.Errorf("error searching LDAP: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		if len(result.Entries) > 1 {
+			log.Printf("LDAP filter matched more than one entry for %s", userName)
+		}
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+//names returns the names of the mapped attributes
+func (a *LDAPAttributes) names() []string {
+	names := []string{}
+	for _, name := range []string{a.UserName, a.PersonalName, a.FamilyName, a.Email} {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+//toUser maps the attributes of entry into a users.User
+func (a *LDAPAttributes) toUser(entry *ldap.Entry) *users.User {
+	value := func(name string) string {
+		if len(name) == 0 {
+			return ""
+		}
+		return entry.GetAttributeValue(name)
+	}
+	return &users.User{
+		UserName:     value(a.UserName),
+		PersonalName: value(a.PersonalName),
+		FamilyName:   value(a.FamilyName),
+		Email:        value(a.Email),
+	}
+}
+
+//provision returns the stored user for the directory profile, creating
+//it if it doesn't yet exist, and updating its mapped fields if they differ.
+//It returns ErrInvalidCredentials if the stored user wasn't provisioned
+//from the directory.
+func provision(store users.Store, profile *users.User) (*users.User, error) {
+	user, err := store.Get(profile.UserName)
+	if errors.Is(err, users.ErrNotFound) {
+		//the directory is authoritative, so its email addresses are verified
+		user, err = users.NewExternalUser(profile.UserName, profile.PersonalName, profile.FamilyName, profile.Email, true)
+		if err != nil {
+			return nil, fmt.Errorf("error provisioning %s: %v", profile.UserName, err)
+		}
+		user.Directory = true
+		err = store.Insert(user)
+		if err == nil {
+			return user, nil
+		}
+		//a concurrent sign-in provisioned the user first
+		if !errors.Is(err, users.ErrUserNameTaken) {
+			return nil, err
+		}
+		user, err = store.Get(profile.UserName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.Directory {
+		log.Printf("refusing LDAP sign-in of %s, whose account wasn't provisioned from the directory", user.UserName)
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.PersonalName == profile.PersonalName && user.FamilyName == profile.FamilyName && user.Email == profile.Email {
+		return user, nil
+	}
+	if user.Email != profile.Email {
+		user.Email = profile.Email
+		user.EmailVerified = len(profile.Email) > 0
+	}
+	user.PersonalName = profile.PersonalName
+	user.FamilyName = profile.FamilyName
+	//the directory is consulted again at the next sign-in,
+	//so a failure to update the profile isn't fatal
+	if err := store.Save(user); err != nil {
+		log.Printf("error updating profile of %s from LDAP: %v", user.UserName, err)
+	}
+	return user, nil
+}
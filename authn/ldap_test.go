@@ -0,0 +1,102 @@
+package authn
+
+import (
+	"testing"
+
+	"github.com/davestearns/userservice/models/users"
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestNewLDAP(t *testing.T) {
+	valid := LDAPConfig{
+		URL:        "ldaps://ldap.example.com",
+		Filter:     "(uid=%s)",
+		Attributes: LDAPAttributes{UserName: "uid"},
+	}
+	if _, err := NewLDAP(&valid, users.NewMemStore()); err != nil {
+		t.Errorf("error constructing with valid config: %v", err)
+	}
+	cases := map[string]func(c *LDAPConfig){
+		"http URL":           func(c *LDAPConfig) { c.URL = "http://ldap.example.com" },
+		"filter without %s":  func(c *LDAPConfig) { c.Filter = "(uid=test)" },
+		"filter with two %s": func(c *LDAPConfig) { c.Filter = "(|(uid=%s)(mail=%s))" },
+		"no user name attr":  func(c *LDAPConfig) { c.Attributes.UserName = "" },
+	}
+	for name, mutate := range cases {
+		config := valid
+		mutate(&config)
+		if _, err := NewLDAP(&config, users.NewMemStore()); err == nil {
+			t.Errorf("%s: expected error but didn't get one", name)
+		}
+	}
+
+	//empty passwords are refused before the directory is contacted
+	l, _ := NewLDAP(&valid, users.NewMemStore())
+	if _, err := l.Authenticate("test", ""); err != ErrInvalidCredentials {
+		t.Errorf("incorrect error for empty password: expected %v but got %v", ErrInvalidCredentials, err)
+	}
+}
+
+func TestLDAPAttributes(t *testing.T) {
+	entry := ldap.NewEntry("cn=Test User,ou=people,dc=example,dc=com", map[string][]string{
+		"sAMAccountName": {"tuser"},
+		"givenName":      {"Test"},
+		"sn":             {"User"},
+		"mail":           {"tuser@example.com"},
+	})
+	attrs := &LDAPAttributes{UserName: "sAMAccountName", PersonalName: "givenName", FamilyName: "sn", Email: "mail"}
+	user := attrs.toUser(entry)
+	if user.UserName != "tuser" || user.PersonalName != "Test" || user.FamilyName != "User" || user.Email != "tuser@example.com" {
+		t.Errorf("incorrect mapping: %+v", user)
+	}
+	if len(attrs.names()) != 4 {
+		t.Errorf("incorrect attribute names: %v", attrs.names())
+	}
+
+	//unmapped attributes are left empty
+	attrs = &LDAPAttributes{UserName: "sAMAccountName"}
+	if user := attrs.toUser(entry); len(user.Email) > 0 || len(user.PersonalName) > 0 {
+		t.Errorf("unmapped attributes should be empty: %+v", user)
+	}
+}
+
+func TestProvision(t *testing.T) {
+	store := users.NewMemStore()
+	profile := &users.User{UserName: "tuser", PersonalName: "Test", FamilyName: "User", Email: "tuser@example.com"}
+	user, err := provision(store, profile)
+	if err != nil {
+		t.Fatalf("error provisioning new user: %v", err)
+	}
+	if user.HasPassword() || !user.EmailVerified || !user.Directory {
+		t.Errorf("provisioned user should have no password, a verified email and the directory marker: %+v", user)
+	}
+	if _, err := store.Get("tuser"); err != nil {
+		t.Fatalf("error getting provisioned user: %v", err)
+	}
+
+	//later sign-ins update the profile from the directory
+	profile.FamilyName = "Renamed"
+	profile.Email = "renamed@example.com"
+	if _, err := provision(store, profile); err != nil {
+		t.Fatalf("error provisioning existing user: %v", err)
+	}
+	stored, err := store.Get("tuser")
+	if err != nil {
+		t.Fatalf("error getting updated user: %v", err)
+	}
+	if stored.FamilyName != "Renamed" || stored.Email != "renamed@example.com" || !stored.EmailVerified {
+		t.Errorf("profile was not updated from the directory: %+v", stored)
+	}
+
+	//local accounts with the same name are neither adopted nor updated
+	local := &users.User{UserName: "local", PasswordHash: []byte("hash"), Email: "local@example.com"}
+	if err := store.Insert(local); err != nil {
+		t.Fatalf("error inserting local user: %v", err)
+	}
+	if _, err := provision(store, &users.User{UserName: "local", Email: "attacker@example.com"}); err != ErrInvalidCredentials {
+		t.Errorf("incorrect error provisioning over a local user: expected %v but got %v", ErrInvalidCredentials, err)
+	}
+	if stored, _ := store.Get("local"); stored.Email != "local@example.com" || stored.Directory {
+		t.Errorf("local user was modified by provisioning: %+v", stored)
+	}
+}
@@ -0,0 +1,54 @@
+package authn
+
+import (
+	"errors"
+	"log"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//Local is an Authenticator that verifies passwords
+//against the password hashes in a users.Store
+type Local struct {
+	store users.Store
+}
+
+//NewLocal constructs a new Local Authenticator
+func NewLocal(store users.Store) *Local {
+	return &Local{store: store}
+}
+
+//Authenticate implements the Authenticator interface. Hashes weaker than
+//those produced by the current users.PasswordHasher are upgraded.
+func (l *Local) Authenticate(userName string, password string) (*users.User, error) {
+	user, err := l.store.Get(userName)
+	if err != nil {
+		if !errors.Is(err, users.ErrNotFound) {
+			return nil, err
+		}
+		users.DummyAuthenticate()
+		return nil, ErrInvalidCredentials
+	}
+	if err := user.Authenticate([]byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	l.upgradePasswordHash(user, password)
+	return user, nil
+}
+
+//upgradePasswordHash rehashes and saves the user's password if the stored
+//hash is weaker than those produced by the current users.PasswordHasher.
+//Errors are logged rather than failing the sign-in, as the stored hash
+//is still valid.
+func (l *Local) upgradePasswordHash(user *users.User, password string) {
+	rehashed, err := user.RehashPassword([]byte(password))
+	if err != nil {
+		log.Printf("error rehashing password for %s: %v", user.UserName, err)
+		return
+	}
+	if rehashed {
+		if err := l.store.Save(user); err != nil {
+			log.Printf("error saving rehashed password for %s: %v", user.UserName, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package authn
+
+import (
+	"testing"
+
+	"github.com/davestearns/userservice/models/users"
+	"github.com/davestearns/userservice/passhash"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLocal(t *testing.T) {
+	users.PasswordHasher = &passhash.Bcrypt{Cost: bcrypt.MinCost}
+	store := users.NewMemStore()
+	nu := &users.NewUser{UserName: "test", Password: "correct horse battery staple"}
+	user, err := nu.ToUser()
+	if err != nil {
+		t.Fatalf("error converting new user: %v", err)
+	}
+	if err := store.Insert(user); err != nil {
+		t.Fatalf("error inserting user: %v", err)
+	}
+
+	local := NewLocal(store)
+	authenticated, err := local.Authenticate("test", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("error authenticating with correct password: %v", err)
+	}
+	if authenticated.UserName != "test" {
+		t.Errorf("incorrect user name: expected %s but got %s", "test", authenticated.UserName)
+	}
+	if _, err := local.Authenticate("test", "wrong password"); err != ErrInvalidCredentials {
+		t.Errorf("incorrect error for wrong password: expected %v but got %v", ErrInvalidCredentials, err)
+	}
+	if _, err := local.Authenticate("nobody", "correct horse battery staple"); err != ErrInvalidCredentials {
+		t.Errorf("incorrect error for unknown user: expected %v but got %v", ErrInvalidCredentials, err)
+	}
+
+	//users without a password can't sign in with one
+	external, _ := users.NewExternalUser("external", "", "", "", false)
+	if err := store.Insert(external); err != nil {
+		t.Fatalf("error inserting external user: %v", err)
+	}
+	if _, err := local.Authenticate("external", ""); err != ErrInvalidCredentials {
+		t.Errorf("incorrect error for user without password: expected %v but got %v", ErrInvalidCredentials, err)
+	}
+}
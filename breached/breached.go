@@ -0,0 +1,46 @@
+//Package breached checks passwords against corpora of passwords exposed
+//in data breaches, such as Have I Been Pwned's Pwned Passwords. Lookups use
+//the k-anonymity range model: passwords are hashed with SHA-1 and only the
+//first five hex characters of the hash are used to query a range of hashes.
+package breached
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+//prefixLength is the number of hex characters in a range prefix
+const prefixLength = 5
+
+//RangeClient returns the hashes within a range
+type RangeClient interface {
+	//Range returns the suffixes (the remaining 35 upper-case hex characters)
+	//of all breached password hashes beginning with prefix, mapped to
+	//the number of times each was seen
+	Range(prefix string) (map[string]int, error)
+}
+
+//Checker checks whether passwords have been breached
+type Checker struct {
+	client RangeClient
+}
+
+//NewChecker constructs a new Checker that queries client
+func NewChecker(client RangeClient) *Checker {
+	return &Checker{
+		client: client,
+	}
+}
+
+//Count returns the number of times password was seen in breaches,
+//which is zero if it wasn't seen
+func (c *Checker) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := c.client.Range(hash[:prefixLength])
+	if err != nil {
+		return 0, err
+	}
+	return suffixes[hash[prefixLength:]], nil
+}
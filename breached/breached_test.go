@@ -0,0 +1,83 @@
+package breached
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//corpus returns "<SHA-1>:<count>" lines for passwords, sorted by hash
+func corpus(passwords map[string]int) []string {
+	var lines []string
+	for p, count := range passwords {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), count))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+//breaches is the corpus used in tests; the number of passwords is
+//large enough that the file search is exercised at many offsets
+func breaches() map[string]int {
+	passwords := map[string]int{"password": 100, "123456": 50, "letmein": 7}
+	for i := 0; i < 500; i++ {
+		passwords[fmt.Sprintf("filler-%d", i)] = i + 1
+	}
+	return passwords
+}
+
+func testChecker(t *testing.T, checker *Checker) {
+	for p, expected := range breaches() {
+		count, err := checker.Count(p)
+		if err != nil {
+			t.Fatalf("error checking '%s': %v", p, err)
+		}
+		if count != expected {
+			t.Errorf("incorrect count for '%s': expected %d but got %d", p, expected, count)
+		}
+	}
+	count, err := checker.Count("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("error checking password: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("password not in corpus should have count 0, but got %d", count)
+	}
+}
+
+func TestFileRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(corpus(breaches()), "\r\n")+"\r\n"), 0600); err != nil {
+		t.Fatalf("error writing corpus: %v", err)
+	}
+	fr, err := NewFileRange(path)
+	if err != nil {
+		t.Fatalf("error opening corpus: %v", err)
+	}
+	defer fr.Close()
+	testChecker(t, NewChecker(fr))
+}
+
+func TestHTTPRange(t *testing.T) {
+	lines := corpus(breaches())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		for _, line := range lines {
+			if strings.HasPrefix(line, prefix) {
+				fmt.Fprintln(w, line[len(prefix):])
+			}
+		}
+		//padding entry
+		fmt.Fprintln(w, "00000000000000000000000000000000000:0")
+	}))
+	defer server.Close()
+	testChecker(t, NewChecker(NewHTTPRange(server.URL+"/range/")))
+}
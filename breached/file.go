@@ -0,0 +1,130 @@
+package breached
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//FileRange is a RangeClient that searches a local copy of a breached
+//password corpus. The file must contain lines in the form "<SHA-1>:<count>",
+//sorted by hash, as in the Pwned Passwords "ordered by hash" download.
+//The file is binary-searched rather than loaded into memory.
+type FileRange struct {
+	mx   sync.Mutex
+	file *os.File
+	size int64
+}
+
+//NewFileRange opens the corpus file at path
+func NewFileRange(path string) (*FileRange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breached password file: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error reading breached password file: %v", err)
+	}
+	return &FileRange{file: f, size: info.Size()}, nil
+}
+
+//Close closes the file
+func (fr *FileRange) Close() error {
+	return fr.file.Close()
+}
+
+//Range returns the suffixes of all hashes in the file beginning with prefix
+func (fr *FileRange) Range(prefix string) (map[string]int, error) {
+	prefix = strings.ToUpper(prefix)
+	fr.mx.Lock()
+	defer fr.mx.Unlock()
+
+	//find the offset of the first line not less than prefix
+	lo, hi := int64(0), fr.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, err := fr.lineAfter(mid)
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 || strings.ToUpper(line) >= prefix {
+			hi = mid
+		} else {
+			lo = start + int64(len(line)) + 1
+		}
+	}
+	_, start, err := fr.lineAfter(lo)
+	if err != nil {
+		return nil, err
+	}
+	suffixes := map[string]int{}
+	if start < 0 {
+		return suffixes, nil
+	}
+
+	if _, err := fr.file.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error reading breached password file: %v", err)
+	}
+	scanner := bufio.NewScanner(fr.file)
+	for scanner.Scan() {
+		line := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+		if !strings.HasPrefix(line, prefix) {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		count := 1
+		if len(parts) == 2 {
+			if n, err := strconv.Atoi(parts[1]); err == nil {
+				count = n
+			}
+		}
+		suffixes[parts[0][len(prefix):]] = count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading breached password file: %v", err)
+	}
+	return suffixes, nil
+}
+
+//lineAfter returns the first complete line starting at or after offset,
+//along with the offset at which it starts. The line at offset 0 is always
+//complete; elsewhere the partial line containing offset is skipped. If there
+//is no such line, the returned start is -1.
+func (fr *FileRange) lineAfter(offset int64) (string, int64, error) {
+	if offset >= fr.size {
+		return "", -1, nil
+	}
+	start := offset
+	if offset > 0 {
+		//back up one byte so that a line starting exactly at offset isn't skipped
+		start = offset - 1
+	}
+	if _, err := fr.file.Seek(start, io.SeekStart); err != nil {
+		return "", -1, fmt.Errorf("error reading breached password file: %v", err)
+	}
+	reader := bufio.NewReader(fr.file)
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return "", -1, nil
+		}
+		if err != nil {
+			return "", -1, fmt.Errorf("error reading breached password file: %v", err)
+		}
+		start += int64(len(skipped))
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", -1, fmt.Errorf("error reading breached password file: %v", err)
+	}
+	if len(line) == 0 {
+		return "", -1, nil
+	}
+	return strings.TrimRight(line, "\r\n"), start, nil
+}
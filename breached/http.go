@@ -0,0 +1,67 @@
+package breached
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//DefaultRangeURL is the base URL of the Pwned Passwords range API
+const DefaultRangeURL = "https://api.pwnedpasswords.com/range/"
+
+//HTTPRange is a RangeClient that queries a Pwned Passwords-compatible
+//range API, which returns lines in the form "<suffix>:<count>"
+type HTTPRange struct {
+	baseURL string
+	client  *http.Client
+}
+
+//NewHTTPRange constructs a new HTTPRange. The prefix is appended to baseURL.
+func NewHTTPRange(baseURL string) *HTTPRange {
+	return &HTTPRange{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+//Range returns the suffixes of all hashes beginning with prefix
+func (hr *HTTPRange) Range(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, hr.baseURL+strings.ToUpper(prefix), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating range request: %v", err)
+	}
+	//padding hides the number of suffixes in the range from observers
+	req.Header.Set("Add-Padding", "true")
+	resp, err := hr.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting range: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status requesting range: %s", resp.Status)
+	}
+
+	suffixes := map[string]int{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		//padding entries have a count of zero
+		if count > 0 {
+			suffixes[strings.ToUpper(parts[0])] = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading range: %v", err)
+	}
+	return suffixes, nil
+}
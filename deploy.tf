@@ -22,6 +22,32 @@ resource "aws_dynamodb_table" "users-table" {
     }
 }
 
+# OAuth clients table
+resource "aws_dynamodb_table" "clients-table" {
+    name = "clients"
+    read_capacity = 5
+    write_capacity = 5
+    hash_key = "id"
+
+    attribute {
+        name = "id"
+        type = "S"
+    }
+}
+
+# linked external identities table
+resource "aws_dynamodb_table" "identity-links-table" {
+    name = "identity_links"
+    read_capacity = 5
+    write_capacity = 5
+    hash_key = "id"
+
+    attribute {
+        name = "id"
+        type = "S"
+    }
+}
+
 # session cache
 resource "aws_security_group" "session-cache-sg" {
     name = "session-cache-sg"
@@ -58,6 +84,7 @@ resource "aws_iam_role" "user-service-role" {
     assume_role_policy = "${data.aws_iam_policy_document.user-service-role-policy.json}"
 }
 
+# full access covers the users, clients and identity_links tables
 resource "aws_iam_role_policy_attachment" "user-service-role-attach-dynamodb" {
     role = "${aws_iam_role.user-service-role.name}"
     policy_arn = "arn:aws:iam::aws:policy/AmazonDynamoDBFullAccess"
@@ -0,0 +1,110 @@
+//Package federation signs users in via external OpenID Connect
+//providers, such as Google, using the authorization-code flow
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+//ErrInvalidNonce is returned from Exchange when the ID token's
+//nonce doesn't match the one sent in the authorization request
+var ErrInvalidNonce = errors.New("ID token nonce doesn't match")
+
+//ProviderConfig is the configuration of an external provider
+type ProviderConfig struct {
+	//Name identifies the provider to clients, e.g. "google"
+	Name string `json:"name"`
+	//Issuer is the provider's issuer URL, from which its
+	//discovery document is fetched
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+	//RedirectURL is the page to which the provider sends the user back
+	RedirectURL string `json:"redirectURL"`
+}
+
+//Identity is a user's identity at an external provider
+type Identity struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+}
+
+//Provider is an external OpenID Connect provider
+type Provider struct {
+	Name     string
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+//NewProvider constructs a new Provider, fetching
+//the provider's discovery document
+func NewProvider(ctx context.Context, cfg *ProviderConfig) (*Provider, error) {
+	discovered, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering provider %s: %v", cfg.Name, err)
+	}
+	return &Provider{
+		Name: cfg.Name,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+//AuthCodeURL returns the URL to which the user should be sent to sign
+//in at the provider. The state, nonce and PKCE code verifier must be
+//kept and supplied to Exchange when the user returns.
+func (p *Provider) AuthCodeURL(state string, nonce string, codeVerifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier))
+}
+
+//Exchange exchanges the authorization code returned by the provider
+//for an ID token, verifies it, and returns the identity it asserts
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging authorization code: %v", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response contained no ID token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying ID token: %v", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+	identity := &Identity{}
+	if err := idToken.Claims(identity); err != nil {
+		return nil, fmt.Errorf("error decoding ID token claims: %v", err)
+	}
+	return identity, nil
+}
+
+//NewCodeVerifier returns a new random PKCE code verifier
+func NewCodeVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+//NewNonce returns a new random nonce, which binds the ID
+//token to the authorization request that obtained it
+func NewNonce() string {
+	return oauth2.GenerateVerifier()
+}
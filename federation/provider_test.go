@@ -0,0 +1,135 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/davestearns/userservice/jwt"
+)
+
+//fakeIssuer is a local OpenID Connect issuer that
+//issues an ID token for a single authorization code
+type fakeIssuer struct {
+	server        *httptest.Server
+	signer        *jwt.Signer
+	code          string
+	codeChallenge string
+	nonce         string
+}
+
+type fakeIDTokenClaims struct {
+	jwt.Claims
+	Nonce string `json:"nonce"`
+	Email string `json:"email"`
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	key, err := jwt.ParseRSAKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)}))
+	if err != nil {
+		t.Fatalf("error parsing RSA key: %v", err)
+	}
+	signer, _ := jwt.NewSigner([]*jwt.Key{key})
+	fi := &fakeIssuer{signer: signer, code: "test-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                fi.server.URL,
+			"authorization_endpoint":                fi.server.URL + "/authorize",
+			"token_endpoint":                        fi.server.URL + "/token",
+			"jwks_uri":                              fi.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{jwt.RS256},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(fi.signer.JWKSet())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		hash := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != fi.code || base64.RawURLEncoding.EncodeToString(hash[:]) != fi.codeChallenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		now := time.Now()
+		idToken, _ := fi.signer.Sign(&fakeIDTokenClaims{
+			Claims: jwt.Claims{
+				Issuer:   fi.server.URL,
+				Subject:  "external-id",
+				Audience: "test-client",
+				IssuedAt: now.Unix(),
+				Expires:  now.Add(time.Minute).Unix(),
+			},
+			Nonce: fi.nonce,
+			Email: "test@example.com",
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	fi.server = httptest.NewServer(mux)
+	return fi
+}
+
+func TestProvider(t *testing.T) {
+	fi := newFakeIssuer(t)
+	defer fi.server.Close()
+	ctx := context.Background()
+
+	provider, err := NewProvider(ctx, &ProviderConfig{
+		Name:         "fake",
+		Issuer:       fi.server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatalf("error constructing provider: %v", err)
+	}
+
+	verifier := NewCodeVerifier()
+	authURL, err := url.Parse(provider.AuthCodeURL("test-state", "test-nonce", verifier))
+	if err != nil {
+		t.Fatalf("error parsing authorization URL: %v", err)
+	}
+	query := authURL.Query()
+	if query.Get("state") != "test-state" || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("incorrect authorization URL: %s", authURL)
+	}
+	fi.codeChallenge = query.Get("code_challenge")
+	fi.nonce = query.Get("nonce")
+
+	identity, err := provider.Exchange(ctx, fi.code, verifier, "test-nonce")
+	if err != nil {
+		t.Fatalf("error exchanging code: %v", err)
+	}
+	if identity.Issuer != fi.server.URL || identity.Subject != "external-id" || identity.Email != "test@example.com" {
+		t.Errorf("incorrect identity: %+v", identity)
+	}
+
+	if _, err := provider.Exchange(ctx, fi.code, verifier, "other-nonce"); err != ErrInvalidNonce {
+		t.Errorf("incorrect error for mismatched nonce: expected %v but got %v", ErrInvalidNonce, err)
+	}
+	if _, err := provider.Exchange(ctx, fi.code, NewCodeVerifier(), "test-nonce"); err == nil {
+		t.Errorf("expected an error exchanging with the wrong code verifier")
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//AdminUsersHandler handles requests for the /admin/users/<username> resource
+//and its /role and /status subresources. Each method and subresource
+//requires the signed-in user to have a different permission on the account.
+func (c *Config) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	userName, subresource := splitAdminUserPath(r.URL.Path)
+	if len(userName) == 0 {
+		respondError(w, newHTTPError(http.StatusNotFound, "no user name in path"))
+		return
+	}
+	switch subresource {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			c.EnsurePermission(users.PermViewPrivate, userName, c.adminGetUser)(w, r)
+		case http.MethodPatch:
+			c.EnsurePermission(users.PermEditProfile, userName, c.updateUser)(w, r)
+		default:
+			respondError(w, errMethodNotAllowed)
+		}
+
+	case "role":
+		if r.Method != http.MethodPut {
+			respondError(w, errMethodNotAllowed)
+			return
+		}
+		c.EnsurePermission(users.PermAssignRole, userName, c.assignRole)(w, r)
+
+	case "status":
+		if r.Method != http.MethodPut {
+			respondError(w, errMethodNotAllowed)
+			return
+		}
+		c.EnsurePermission(users.PermSetStatus, userName, c.setStatus)(w, r)
+
+	default:
+		respondError(w, newHTTPError(http.StatusNotFound, "unknown resource '%s'", subresource))
+	}
+}
+
+//adminGetUser responds with the private view of target
+func (c *Config) adminGetUser(w http.ResponseWriter, r *http.Request, sessionState *SessionState, target *users.User) {
+	w.Header().Set(headerETag, etag(target))
+	respond(w, target.Private(), http.StatusOK)
+}
+
+//assignRole changes the role of target to the posted role
+func (c *Config) assignRole(w http.ResponseWriter, r *http.Request, sessionState *SessionState, target *users.User) {
+	assignment := &users.RoleAssignment{}
+	if err := receive(r, assignment); err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted role: %v", err))
+		return
+	}
+	role, err := users.ParseRole(assignment.Role)
+	if err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	target.Role = role
+	if err := c.UserStore.Save(target); err != nil {
+		respondError(w, err)
+		return
+	}
+	log.Printf("%s assigned role '%s' to %s", sessionState.User.UserName, role, target.UserName)
+	w.Header().Set(headerETag, etag(target))
+	respond(w, target.Private(), http.StatusOK)
+}
+
+//setStatus suspends or reactivates the account of target; reactivating an
+//account whose deletion is scheduled restores it, while suspending it keeps
+//the schedule. Suspending ends all of its sessions;
+//its access and refresh tokens stop working because EnsureSession and the
+//token endpoint reject accounts that aren't active.
+func (c *Config) setStatus(w http.ResponseWriter, r *http.Request, sessionState *SessionState, target *users.User) {
+	change := &users.StatusChange{}
+	if err := receive(r, change); err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted status: %v", err))
+		return
+	}
+	status, err := users.ParseStatus(change.Status)
+	if err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if err := target.SetStatus(status); err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if err := c.UserStore.Save(target); err != nil {
+		respondError(w, err)
+		return
+	}
+	if status == users.StatusSuspended {
+		if err := c.endAllSessions(target.UserName); err != nil {
+			log.Printf("error ending sessions of suspended user '%s': %v", target.UserName, err)
+		}
+	}
+	log.Printf("%s set status '%s' for %s", sessionState.User.UserName, status, target.UserName)
+	w.Header().Set(headerETag, etag(target))
+	respond(w, target.Private(), http.StatusOK)
+}
+
+//splitAdminUserPath splits a /admin/users/<username>[/<subresource>]
+//path into the user name and subresource
+func splitAdminUserPath(p string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(p, "/admin/users/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
@@ -1,12 +1,125 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/davestearns/sessions"
+	"github.com/davestearns/userservice/authn"
+	"github.com/davestearns/userservice/federation"
+	"github.com/davestearns/userservice/jwt"
+	"github.com/davestearns/userservice/mailer"
+	"github.com/davestearns/userservice/models/authcodes"
+	"github.com/davestearns/userservice/models/challenges"
+	"github.com/davestearns/userservice/models/clients"
+	"github.com/davestearns/userservice/models/identities"
+	"github.com/davestearns/userservice/models/ratelimit"
+	"github.com/davestearns/userservice/models/refreshtokens"
+	"github.com/davestearns/userservice/models/resets"
+	"github.com/davestearns/userservice/models/smscodes"
+	"github.com/davestearns/userservice/models/throttle"
 	"github.com/davestearns/userservice/models/users"
+	"github.com/davestearns/userservice/models/usersessions"
+	"github.com/davestearns/userservice/sealing"
+	"github.com/davestearns/userservice/signing"
+	"github.com/davestearns/userservice/sms"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 //Config holds the global configuration values for handlers
 type Config struct {
 	SessionManager sessions.Manager
 	UserStore      users.Store
+	ResetStore     resets.Store
+	Mailer         mailer.Mailer
+	//ResetURL is the URL included in password reset emails;
+	//the reset token is appended to it
+	ResetURL string
+	//ResetTTL is how long password reset tokens remain valid
+	ResetTTL time.Duration
+	//EmailSigner signs and verifies email verification tokens
+	EmailSigner *signing.Signer
+	//VerifyEmailURL is the URL included in email verification messages;
+	//the verification token is appended to it
+	VerifyEmailURL string
+	//VerifyEmailTTL is how long email verification links remain valid
+	VerifyEmailTTL time.Duration
+	SMSCodeStore   smscodes.Store
+	SMSSender      sms.Sender
+	//SMSCodeTTL is how long mobile verification codes remain valid
+	SMSCodeTTL time.Duration
+	//MFASealer seals and opens users' TOTP secrets
+	MFASealer *sealing.Sealer
+	//MFAIssuer is the issuer name shown in authenticator apps
+	MFAIssuer string
+	//MFASigner signs and verifies pending-MFA sign-in challenges
+	MFASigner *signing.Signer
+	//MFAChallengeTTL is how long a pending-MFA sign-in challenge remains valid
+	MFAChallengeTTL time.Duration
+	//WebAuthn performs passkey registration and sign-in ceremonies
+	WebAuthn *webauthn.WebAuthn
+	//ChallengeStore holds the state of pending passkey ceremonies
+	ChallengeStore challenges.Store
+	//PasskeyChallengeTTL is how long a passkey ceremony may take
+	PasskeyChallengeTTL time.Duration
+	//UserThrottler tracks failed sign-in attempts by user name
+	UserThrottler *throttle.Throttler
+	//IPThrottler tracks failed sign-in attempts by client IP address
+	IPThrottler *throttle.Throttler
+	//RateLimitRules are the per-route rate limits applied by the
+	//RateLimit middleware; the first matching rule applies
+	RateLimitRules []*ratelimit.Rule
+	//RateLimitStore holds the rate limit token buckets
+	RateLimitStore ratelimit.Store
+	//SessionStore is the store used by SessionManager, which is
+	//used directly to end sessions other than the current one
+	SessionStore sessions.Store
+	//SessionIndex is the index of each user's sessions
+	SessionIndex usersessions.Store
+	//SessionLifetime is the lifetime of sessions
+	SessionLifetime SessionLifetime
+	//RememberedSessionLifetime is the lifetime of sessions
+	//begun by users who asked to be remembered
+	RememberedSessionLifetime SessionLifetime
+	//AccessTokenSigner signs and verifies JWT access tokens
+	AccessTokenSigner *jwt.Signer
+	//TokenIssuer is the issuer of JWT access tokens
+	TokenIssuer string
+	//AccessTokenTTL is how long JWT access tokens last
+	AccessTokenTTL time.Duration
+	//RefreshTokenStore holds refresh tokens
+	RefreshTokenStore refreshtokens.Store
+	//RefreshTokenTTL is how long refresh tokens last
+	RefreshTokenTTL time.Duration
+	//ClientStore holds the registered OpenID Connect clients
+	ClientStore clients.Store
+	//AuthCodeStore holds authorization codes
+	AuthCodeStore authcodes.Store
+	//AuthCodeTTL is how long authorization codes last
+	AuthCodeTTL time.Duration
+	//SignInURL is the URL of the sign-in page to which the authorization
+	//endpoint redirects; the authorization request query is appended to it
+	SignInURL string
+	//ExternalProviders are the external OpenID Connect providers
+	//users may sign in with, keyed by name
+	ExternalProviders map[string]*federation.Provider
+	//IdentityStore holds the external identities linked to users
+	IdentityStore identities.Store
+	//ExternalSignInTTL is how long users have to
+	//complete a sign-in via an external provider
+	ExternalSignInTTL time.Duration
+	//Authenticator verifies the user names and passwords
+	//with which users sign in and request tokens
+	Authenticator authn.Authenticator
+	//DeletionGracePeriod is how long deleted accounts
+	//may be restored before they are purged
+	DeletionGracePeriod time.Duration
+	//SMSSendLimit limits the verification codes sent
+	//to each user, and to each mobile number
+	SMSSendLimit ratelimit.Limit
+	//RefreshFamilyMaxAge is how long after signing in a refresh
+	//token family may be rotated before the user must sign in again
+	RefreshFamilyMaxAge time.Duration
+	//SignUpDisabled prevents users from creating their own accounts,
+	//which is set when accounts are provisioned from a directory
+	SignUpDisabled bool
 }
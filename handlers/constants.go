@@ -3,6 +3,17 @@ package handlers
 const (
 	headerContentType = "Content-Type"
 	headerLocation    = "Location"
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerRetryAfter  = "Retry-After"
+
+	headerAuthorization   = "Authorization"
+	headerCacheControl    = "Cache-Control"
+	headerWWWAuthenticate = "WWW-Authenticate"
+
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
 )
 
 const (
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/davestearns/userservice/authn"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//purgeBatchSize is the most accounts purged in each pass of the purger
+const purgeBatchSize = 100
+
+//AccountRestorationsHandler handles requests for the /account-restorations
+//resource, which restores an account pending deletion and signs the user in.
+//Users who can't sign in with a password must ask an administrator to
+//restore their accounts.
+func (c *Config) AccountRestorationsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		creds := &users.Credentials{}
+		if err := receive(r, creds); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted credentials: %v", err))
+			return
+		}
+		if !c.ensureNotLockedOut(w, r, creds.UserName) {
+			return
+		}
+		user, err := c.Authenticator.Authenticate(creds.UserName, creds.Password)
+		if err != nil {
+			if err == authn.ErrInvalidCredentials {
+				c.respondSignInFailure(w, r, creds.UserName, invalidCredentials)
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		if user.EffectiveStatus() != users.StatusPendingDeletion {
+			respondError(w, newHTTPError(http.StatusConflict, "your account is not scheduled for deletion"))
+			return
+		}
+		//users with MFA enabled are restored only once they complete the challenge
+		if user.TOTPEnabled {
+			c.respondMFAChallenge(w, user, creds.RememberMe, true)
+			return
+		}
+		user.SetStatus(users.StatusActive)
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		c.beginSession(w, r, user, creds.RememberMe)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//RunPurger permanently deletes accounts whose deletion grace periods have
+//passed, checking every interval until the process exits. Several instances
+//may run the purger at once, as users.PurgeUser deletes each account only
+//at the version that was found to be purgeable.
+func (c *Config) RunPurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.purgeDeletedUsers(time.Now()); err != nil {
+			log.Printf("error purging deleted users: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+//purgeDeletedUsers permanently deletes the accounts whose grace periods
+//have passed at now, after removing their linked identities, refresh
+//tokens and sessions
+func (c *Config) purgeDeletedUsers(now time.Time) error {
+	due, err := c.UserStore.ListPurgeable(now, purgeBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, userName := range due {
+		purged, err := users.PurgeUser(c.UserStore, userName, now, c.revokeAccess)
+		if err != nil {
+			log.Printf("error purging user '%s': %v", userName, err)
+			continue
+		}
+		if purged {
+			log.Printf("purged deleted account '%s'", userName)
+		}
+	}
+	return nil
+}
+
+//revokeAccess removes everything other than the account itself that grants
+//access to user's account, so that none of it passes to a new account that
+//reuses the name once the account is purged
+func (c *Config) revokeAccess(user *users.User) error {
+	if err := c.IdentityStore.DeleteAll(user.UserName); err != nil {
+		return fmt.Errorf("error unlinking identities: %v", err)
+	}
+	if err := c.RefreshTokenStore.RevokeAll(user.UserName); err != nil {
+		return fmt.Errorf("error revoking refresh tokens: %v", err)
+	}
+	if err := c.endAllSessions(user.UserName); err != nil {
+		return fmt.Errorf("error ending sessions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/davestearns/userservice/mailer"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//emailVerificationClaims are the claims within a signed email verification token
+type emailVerificationClaims struct {
+	UserName string `json:"userName"`
+	Email    string `json:"email"`
+}
+
+//EmailVerificationsHandler handles requests for the /users/me/email-verifications resource
+func (c *Config) EmailVerificationsHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		//(re)send the verification email for the current address
+		user, err := c.UserStore.Get(sessionState.User.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if len(user.Email) == 0 {
+			respondError(w, newHTTPError(http.StatusBadRequest, "your profile has no email address to verify"))
+			return
+		}
+		if user.EmailVerified {
+			respondError(w, newHTTPError(http.StatusConflict, "your email address is already verified"))
+			return
+		}
+		if err := c.sendEmailVerification(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("verification email sent"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SpecificEmailVerificationHandler handles requests for the /email-verifications/<token> resource
+func (c *Config) SpecificEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		claims := &emailVerificationClaims{}
+		if err := c.EmailSigner.Verify(path.Base(r.URL.Path), claims); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "invalid or expired verification link: %v", err))
+			return
+		}
+		user, err := c.UserStore.Get(claims.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		//the address may have changed since the link was sent
+		if !strings.EqualFold(user.Email, claims.Email) {
+			respondError(w, newHTTPError(http.StatusConflict, "your email address has changed since this link was sent"))
+			return
+		}
+		if !user.EmailVerified {
+			user.EmailVerified = true
+			if err := c.UserStore.Save(user); err != nil {
+				respondError(w, err)
+				return
+			}
+		}
+		respond(w, user.Private(), http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//sendEmailVerification emails a signed verification link to the user's current address
+func (c *Config) sendEmailVerification(user *users.User) error {
+	token, err := c.EmailSigner.Sign(&emailVerificationClaims{
+		UserName: user.UserName,
+		Email:    user.Email,
+	}, c.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Please verify that this is the email address for the account '%s' by visiting "+
+			"the following link within %v:\n\n%s%s\n\n"+
+			"If you didn't add this address to an account, you can ignore this message.",
+			user.UserName, c.VerifyEmailTTL, c.VerifyEmailURL, url.PathEscape(token)),
+	})
+}
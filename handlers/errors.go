@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//httpError is an error that should be reported to the
+//client with a specific HTTP status code and message
+type httpError struct {
+	status  int
+	message string
+}
+
+func (he *httpError) Error() string {
+	return he.message
+}
+
+//newHTTPError constructs a new httpError with a formatted message
+func newHTTPError(status int, format string, args ...interface{}) error {
+	return &httpError{
+		status:  status,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+//errorResponse is the JSON body written for all error responses
+type errorResponse struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+//respondError maps err to an HTTP status code and writes a structured JSON
+//error response. Errors that don't map to a specific status are logged and
+//reported as a generic 500 so that internal details never reach the client.
+func respondError(w http.ResponseWriter, err error) {
+	status, message := statusFor(err)
+	if status >= http.StatusInternalServerError {
+		log.Printf("error handling request: %v", err)
+	}
+	respond(w, &errorResponse{
+		Status:  status,
+		Error:   http.StatusText(status),
+		Message: message,
+	}, status)
+}
+
+//statusFor returns the HTTP status code and client-safe message for err
+func statusFor(err error) (int, string) {
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		return he.status, he.message
+	case errors.Is(err, users.ErrNotFound):
+		return http.StatusNotFound, users.ErrNotFound.Error()
+	case errors.Is(err, users.ErrConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, users.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, users.ErrVersionMismatch.Error()
+	case errors.Is(err, users.ErrNothingToUpdate):
+		return http.StatusBadRequest, users.ErrNothingToUpdate.Error()
+	case errors.Is(err, users.ErrUnavailable):
+		return http.StatusServiceUnavailable, "the service is temporarily unavailable; please try again later"
+	default:
+		return http.StatusInternalServerError, "internal server error"
+	}
+}
+
+//errMethodNotAllowed is returned for requests using an unsupported method
+var errMethodNotAllowed = newHTTPError(http.StatusMethodNotAllowed, "method not allowed")
+
+//throttleUnavailable wraps an error from the sign-in throttle's
+//store so that it's reported as a 503, like user store failures
+func throttleUnavailable(err error) error {
+	return fmt.Errorf("%w: error using throttle store: %v", users.ErrUnavailable, err)
+}
+
+//rateLimitUnavailable wraps an error from the rate limit
+//store so that it's reported as a 503, like user store failures
+func rateLimitUnavailable(err error) error {
+	return fmt.Errorf("%w: error using rate limit store: %v", users.ErrUnavailable, err)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//etag returns the entity tag for the current version of user
+func etag(user *users.User) string {
+	return `"` + strconv.Itoa(user.Version) + `"`
+}
+
+//ifMatchVersion returns the user version required by the request's
+//If-Match header, or users.AnyVersion if the header is absent or "*".
+//A header that can't be a version of any user results in a 412 error.
+func ifMatchVersion(r *http.Request) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get(headerIfMatch))
+	if len(ifMatch) == 0 || ifMatch == "*" {
+		return users.AnyVersion, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil || version <= 0 {
+		return 0, newHTTPError(http.StatusPreconditionFailed, "If-Match header does not match the current version")
+	}
+	return version, nil
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/davestearns/userservice/federation"
+	"github.com/davestearns/userservice/models/challenges"
+	"github.com/davestearns/userservice/models/identities"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//maxUserNameAttempts is the number of user names tried
+//when creating an account for an external identity
+const maxUserNameAttempts = 5
+
+//externalStateCookie is the name of the cookie that binds a sign-in via an
+//external provider to the browser that began it, so that a user can't be
+//signed in to another user's account by following a link with its state
+const externalStateCookie = "external_state"
+
+//externalCallbackPath is the path to which the external state cookie is sent
+const externalCallbackPath = "/sessions/external/callback"
+
+//pendingExternalSignIn is the state of a sign-in via an external provider,
+//which is held in a challenge until the user returns from the provider
+type pendingExternalSignIn struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	RememberMe   bool   `json:"rememberMe,omitempty"`
+}
+
+//externalAuthorization is returned when a sign-in via an external provider begins
+type externalAuthorization struct {
+	//AuthorizationURL is where the user's browser should be sent
+	AuthorizationURL string `json:"authorizationURL"`
+}
+
+//identityView is the view of a linked identity returned to its owner
+type identityView struct {
+	ID       string    `json:"id"`
+	Provider string    `json:"provider"`
+	Email    string    `json:"email,omitempty"`
+	Linked   time.Time `json:"linked"`
+}
+
+//newIdentityView constructs a new identityView
+func newIdentityView(link *identities.Link) *identityView {
+	return &identityView{
+		ID:       link.ID,
+		Provider: link.Provider,
+		Email:    link.Email,
+		Linked:   link.Linked,
+	}
+}
+
+//ExternalSessionsHandler handles requests for the /sessions/external
+//resource, which begins a sign-in via an external provider
+func (c *Config) ExternalSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		req := &identities.SignInRequest{}
+		if err := receive(r, req); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted sign-in request: %v", err))
+			return
+		}
+		c.beginExternalSignIn(w, req, "")
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//ExternalSessionsCallbackHandler handles requests for the resource
+//at /sessions/external/callback, to which the redirect page posts
+//the parameters with which the provider sent the user back. The state must
+//match the cookie set when the sign-in began. Users signing in with an
+//identity that isn't linked to an account get a new account, unless
+//sign-up is disabled.
+func (c *Config) ExternalSessionsCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		callback := &identities.Callback{}
+		if err := receive(r, callback); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted callback: %v", err))
+			return
+		}
+		cookie, err := r.Cookie(externalStateCookie)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(callback.State)) != 1 {
+			respondError(w, newHTTPError(http.StatusBadRequest, "sign-in was not begun by this browser"))
+			return
+		}
+		setExternalStateCookie(w, "", -1)
+		challenge, err := c.ChallengeStore.Take(challenges.HashID(callback.State))
+		if err != nil {
+			if err == challenges.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		pending := &pendingExternalSignIn{}
+		if err := challenge.Decode(pending); err != nil {
+			respondError(w, err)
+			return
+		}
+		provider, found := c.ExternalProviders[pending.Provider]
+		if !found {
+			respondError(w, newHTTPError(http.StatusBadRequest, "unknown provider '%s'", pending.Provider))
+			return
+		}
+		identity, err := provider.Exchange(r.Context(), callback.Code, pending.CodeVerifier, pending.Nonce)
+		if err != nil {
+			log.Printf("error completing sign-in via %s: %v", provider.Name, err)
+			respondError(w, newHTTPError(http.StatusUnauthorized, "sign-in via %s failed", provider.Name))
+			return
+		}
+
+		//challenges begun by signed-in users link the identity to their account
+		if len(challenge.UserName) > 0 {
+			c.linkIdentity(w, r, challenge.UserName, provider.Name, identity)
+			return
+		}
+
+		var user *users.User
+		link, err := c.IdentityStore.Get(identity.Issuer, identity.Subject)
+		switch {
+		case err == identities.ErrNotFound:
+			//new accounts could take the names of directory users
+			if c.SignUpDisabled {
+				respondError(w, newHTTPError(http.StatusForbidden, "no account is linked to this identity; sign in and link it first"))
+				return
+			}
+			user, err = c.createExternalUser(provider.Name, identity)
+		case err == nil:
+			user, err = c.UserStore.Get(link.UserName)
+			//the account was purged without removing its links
+			if errors.Is(err, users.ErrNotFound) {
+				if err := c.IdentityStore.Delete(link.UserName, link.ID); err != nil {
+					log.Printf("error unlinking identity of deleted user '%s': %v", link.UserName, err)
+				}
+				respondError(w, newHTTPError(http.StatusUnauthorized, invalidCredentials))
+				return
+			}
+		}
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := ensureNotDirectory(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		//the provider is only a first factor
+		if user.TOTPEnabled {
+			c.respondMFAChallenge(w, user, pending.RememberMe, false)
+			return
+		}
+		c.beginSession(w, r, user, pending.RememberMe)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//IdentitiesHandler handles requests for the /users/me/identities resource
+func (c *Config) IdentitiesHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodGet:
+		links, err := c.IdentityStore.List(sessionState.User.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		views := []*identityView{}
+		for _, link := range links {
+			views = append(views, newIdentityView(link))
+		}
+		respond(w, views, http.StatusOK)
+
+	case http.MethodPost:
+		//begin linking an identity, which completes at /sessions/external/callback
+		if err := ensureNotDirectory(sessionState.User); err != nil {
+			respondError(w, err)
+			return
+		}
+		req := &identities.SignInRequest{}
+		if err := receive(r, req); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted link request: %v", err))
+			return
+		}
+		c.beginExternalSignIn(w, req, sessionState.User.UserName)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SpecificIdentityHandler handles requests for the /users/me/identities/<id> resource
+func (c *Config) SpecificIdentityHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodDelete:
+		user := sessionState.User
+		links, err := c.IdentityStore.List(user.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if !user.HasPassword() && len(user.Passkeys) == 0 && len(links) == 1 {
+			respondError(w, newHTTPError(http.StatusConflict, "you can't unlink your only way to sign in"))
+			return
+		}
+		if err := c.IdentityStore.Delete(user.UserName, path.Base(r.URL.Path)); err != nil {
+			if err == identities.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusNotFound, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("identity unlinked"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//beginExternalSignIn begins a sign-in via the requested provider, or
+//linking an identity to the account of userName if not empty, and
+//responds with the URL to which the user should be sent
+func (c *Config) beginExternalSignIn(w http.ResponseWriter, req *identities.SignInRequest, userName string) {
+	provider, found := c.ExternalProviders[req.Provider]
+	if !found {
+		respondError(w, newHTTPError(http.StatusBadRequest, "unknown provider '%s'", req.Provider))
+		return
+	}
+	pending := &pendingExternalSignIn{
+		Provider:     provider.Name,
+		Nonce:        federation.NewNonce(),
+		CodeVerifier: federation.NewCodeVerifier(),
+		RememberMe:   req.RememberMe,
+	}
+	//the challenge ID is the OAuth state parameter
+	state, challenge, err := challenges.NewChallenge(userName, pending, c.ExternalSignInTTL)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if err := c.ChallengeStore.Insert(challenge); err != nil {
+		respondError(w, err)
+		return
+	}
+	setExternalStateCookie(w, state, int(c.ExternalSignInTTL/time.Second))
+	respond(w, &externalAuthorization{
+		AuthorizationURL: provider.AuthCodeURL(state, pending.Nonce, pending.CodeVerifier),
+	}, http.StatusOK)
+}
+
+//setExternalStateCookie sets the external state cookie to state for maxAge
+//seconds, or clears it if maxAge is negative. The cookie is sent only to
+//the callback, and only by the browser, not scripts.
+func setExternalStateCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     externalStateCookie,
+		Value:    state,
+		Path:     externalCallbackPath,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+//linkIdentity links identity to the account of userName, who must
+//still be signed in, and responds with the new link
+func (c *Config) linkIdentity(w http.ResponseWriter, r *http.Request, userName string, providerName string, identity *federation.Identity) {
+	sessionState, err := c.requestState(r)
+	if err != nil || sessionState.UserName != userName {
+		respondError(w, newHTTPError(http.StatusUnauthorized, "please sign in"))
+		return
+	}
+	link := identities.NewLink(providerName, identity.Issuer, identity.Subject, userName, identity.Email)
+	if err := c.IdentityStore.Insert(link); err != nil {
+		if err == identities.ErrAlreadyLinked {
+			respondError(w, newHTTPError(http.StatusConflict, err.Error()))
+			return
+		}
+		respondError(w, err)
+		return
+	}
+	respond(w, newIdentityView(link), http.StatusCreated)
+}
+
+//createExternalUser creates an account without a password for
+//identity and links the identity to it
+func (c *Config) createExternalUser(providerName string, identity *federation.Identity) (*users.User, error) {
+	base := externalUserName(providerName, identity)
+	for attempt := 0; attempt < maxUserNameAttempts; attempt++ {
+		userName := base
+		if attempt > 0 {
+			userName = fmt.Sprintf("%s%d", base, 1000+rand.Intn(9000))
+		}
+		user, err := users.NewExternalUser(userName, identity.GivenName, identity.FamilyName, identity.Email, identity.EmailVerified)
+		if err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "error creating account: %v", err)
+		}
+		if err := c.UserStore.Insert(user); err != nil {
+			if errors.Is(err, users.ErrUserNameTaken) {
+				continue
+			}
+			return nil, err
+		}
+		link := identities.NewLink(providerName, identity.Issuer, identity.Subject, userName, identity.Email)
+		if err := c.IdentityStore.Insert(link); err != nil {
+			//a concurrent sign-in with the same identity created an
+			//account first, so remove this one and use that one
+			c.UserStore.Delete(userName, users.AnyVersion)
+			if err == identities.ErrAlreadyLinked {
+				if link, err = c.IdentityStore.Get(identity.Issuer, identity.Subject); err == nil {
+					return c.UserStore.Get(link.UserName)
+				}
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, newHTTPError(http.StatusConflict, "couldn't find an available user name for '%s'", base)
+}
+
+//externalUserName returns the preferred user name for a new account for
+//identity: the provider's preferred username, else the local part of the
+//email address, else a name derived from the provider and subject
+func externalUserName(providerName string, identity *federation.Identity) string {
+	for _, candidate := range []string{identity.PreferredUsername, identity.Email} {
+		if i := strings.Index(candidate, "@"); i >= 0 {
+			candidate = candidate[:i]
+		}
+		if len(candidate) > 0 {
+			return candidate
+		}
+	}
+	subject := identity.Subject
+	if len(subject) > 8 {
+		subject = subject[:8]
+	}
+	return providerName + "-" + subject
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//mfaChallengeClaims are the claims within a signed pending-MFA sign-in challenge
+type mfaChallengeClaims struct {
+	UserName string `json:"userName"`
+	//CredentialsChanged ensures the challenge can't be completed
+	//after the user's credentials change
+	CredentialsChanged time.Time `json:"credentialsChanged"`
+	//RememberMe is the remember-me flag from the sign-in credentials
+	RememberMe bool `json:"rememberMe,omitempty"`
+	//Restore is set when the challenge was issued by /account-restorations,
+	//so the account is restored only once the challenge is completed
+	Restore bool `json:"restore,omitempty"`
+}
+
+//mfaChallenge is returned from POST /sessions when the user has MFA enabled
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfaRequired"`
+	Challenge   string `json:"challenge"`
+}
+
+//recoveryCodes is returned when TOTP enrollment is confirmed
+type recoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+//TOTPHandler handles requests for the /users/me/mfa/totp resource
+func (c *Config) TOTPHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	user := sessionState.User
+	switch r.Method {
+	case http.MethodPost:
+		//begin enrollment; a pending enrollment is replaced
+		if user.TOTPEnabled {
+			respondError(w, newHTTPError(http.StatusConflict, "two-factor authentication is already enabled"))
+			return
+		}
+		enrollment, err := user.BeginTOTPEnrollment(c.MFASealer, c.MFAIssuer)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, enrollment, http.StatusCreated)
+
+	case http.MethodDelete:
+		//disabling requires a current code
+		code := &users.MFACode{}
+		if err := receive(r, code); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted code: %v", err))
+			return
+		}
+		if err := user.VerifyMFA(c.MFASealer, code.Code); err != nil {
+			respondError(w, mfaError(err))
+			return
+		}
+		user.DisableTOTP()
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, user.Private(), http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//TOTPConfirmationHandler handles requests for the /users/me/mfa/totp/confirmation resource
+func (c *Config) TOTPConfirmationHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		code := &users.MFACode{}
+		if err := receive(r, code); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted code: %v", err))
+			return
+		}
+		user := sessionState.User
+		if user.TOTPEnabled {
+			respondError(w, newHTTPError(http.StatusConflict, "two-factor authentication is already enabled"))
+			return
+		}
+		plaintexts, err := user.ConfirmTOTPEnrollment(c.MFASealer, code.Code)
+		if err != nil {
+			if err == users.ErrTOTPNotEnrolled {
+				respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+				return
+			}
+			respondError(w, mfaError(err))
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, &recoveryCodes{RecoveryCodes: plaintexts}, http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SessionsMFAHandler handles requests for the /sessions/mfa resource,
+//which completes a sign-in that requires a second factor
+func (c *Config) SessionsMFAHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		response := &users.MFAChallengeResponse{}
+		if err := receive(r, response); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted challenge response: %v", err))
+			return
+		}
+		claims := &mfaChallengeClaims{}
+		if err := c.MFASigner.Verify(response.Challenge, claims); err != nil {
+			respondError(w, newHTTPError(http.StatusUnauthorized, "invalid or expired challenge; please sign in again"))
+			return
+		}
+		if !c.ensureNotLockedOut(w, r, claims.UserName) {
+			return
+		}
+		user, err := c.UserStore.Get(claims.UserName)
+		if err != nil {
+			if errors.Is(err, users.ErrNotFound) {
+				respondError(w, newHTTPError(http.StatusUnauthorized, invalidCredentials))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		if !user.CredentialsChanged.Equal(claims.CredentialsChanged) {
+			respondError(w, newHTTPError(http.StatusUnauthorized, "invalid or expired challenge; please sign in again"))
+			return
+		}
+		if err := user.VerifyMFA(c.MFASealer, response.Code); err != nil {
+			if err == users.ErrInvalidMFACode {
+				c.respondSignInFailure(w, r, user.UserName, err.Error())
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		if claims.Restore && user.EffectiveStatus() == users.StatusPendingDeletion {
+			user.SetStatus(users.StatusActive)
+		}
+		//saving records that the code was used; a version mismatch
+		//means the same code was used concurrently
+		if err := c.UserStore.Save(user); err != nil {
+			if errors.Is(err, users.ErrVersionMismatch) {
+				respondError(w, newHTTPError(http.StatusUnauthorized, users.ErrInvalidMFACode.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		c.beginSession(w, r, user, claims.RememberMe)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//respondMFAChallenge responds with a challenge that must be
+//completed at /sessions/mfa before a session begins; if restore is set,
+//completing it also restores an account pending deletion
+func (c *Config) respondMFAChallenge(w http.ResponseWriter, user *users.User, remember bool, restore bool) {
+	challenge, err := c.MFASigner.Sign(&mfaChallengeClaims{
+		UserName:           user.UserName,
+		CredentialsChanged: user.CredentialsChanged,
+		RememberMe:         remember,
+		Restore:            restore,
+	}, c.MFAChallengeTTL)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Add(headerLocation, "/sessions/mfa")
+	respond(w, &mfaChallenge{MFARequired: true, Challenge: challenge}, http.StatusAccepted)
+}
+
+//mfaError maps ErrInvalidMFACode to a 401, passing other errors through
+func mfaError(err error) error {
+	if err == users.ErrInvalidMFACode {
+		return newHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/davestearns/userservice/models/smscodes"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//MobileVerificationsHandler handles requests for the /users/me/mobile-verifications resource
+func (c *Config) MobileVerificationsHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		//(re)send a verification code to the current mobile number
+		user := sessionState.User
+		if len(user.Mobile) == 0 {
+			respondError(w, newHTTPError(http.StatusBadRequest, "your profile has no mobile number to verify"))
+			return
+		}
+		if user.MobileVerified {
+			respondError(w, newHTTPError(http.StatusConflict, "your mobile number is already verified"))
+			return
+		}
+		if !c.allowSMSSend(w, user) {
+			return
+		}
+		plaintext, code, err := smscodes.NewCode(user.UserName, user.Mobile, c.SMSCodeTTL)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		//incorrect attempts carry over to the new code,
+		//so that re-sending can't be used to reset them
+		pending, err := c.SMSCodeStore.Get(user.UserName)
+		if err != nil && err != smscodes.ErrNotFound {
+			respondError(w, err)
+			return
+		}
+		if err == nil && pending.Mobile == user.Mobile {
+			code.Attempts = pending.Attempts
+		}
+		if err := c.SMSCodeStore.Save(code); err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.SMSSender.Send(user.Mobile, fmt.Sprintf("Your verification code is %s", plaintext)); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("verification code sent"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//MobileVerificationConfirmationHandler handles requests for
+//the /users/me/mobile-verifications/confirmation resource
+func (c *Config) MobileVerificationConfirmationHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		confirmation := &smscodes.Confirmation{}
+		if err := receive(r, confirmation); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted confirmation: %v", err))
+			return
+		}
+		user := sessionState.User
+		code, err := c.SMSCodeStore.Get(user.UserName)
+		if err != nil {
+			if err == smscodes.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		//the number may have changed since the code was sent
+		if code.Mobile != user.Mobile {
+			c.SMSCodeStore.Delete(user.UserName)
+			respondError(w, newHTTPError(http.StatusConflict, "your mobile number has changed since this code was sent"))
+			return
+		}
+		//every attempt is counted before the code is compared, so that
+		//concurrent guesses can't exceed the limit; exhausted codes are kept
+		//until they expire, so that their attempts carry over to new codes
+		attempts, err := c.SMSCodeStore.IncrementAttempts(user.UserName)
+		if err != nil {
+			if err == smscodes.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusBadRequest, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		if attempts > smscodes.MaxAttempts {
+			respondError(w, newHTTPError(http.StatusBadRequest,
+				"too many incorrect attempts; please request a new code after %v", c.SMSCodeTTL))
+			return
+		}
+		if !code.Matches(confirmation.Code) {
+			if attempts == smscodes.MaxAttempts {
+				respondError(w, newHTTPError(http.StatusBadRequest,
+					"incorrect code; too many attempts, so please request a new code after %v", c.SMSCodeTTL))
+				return
+			}
+			respondError(w, newHTTPError(http.StatusBadRequest, "incorrect code"))
+			return
+		}
+
+		if err := c.SMSCodeStore.Delete(user.UserName); err != nil {
+			respondError(w, err)
+			return
+		}
+		user.MobileVerified = true
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, user.Private(), http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//allowSMSSend takes a token from the user's and the mobile number's SMS send
+//buckets, responding with a 429 and returning false if either is empty
+func (c *Config) allowSMSSend(w http.ResponseWriter, user *users.User) bool {
+	for _, key := range []string{"smssend-user:" + user.UserName, "smssend-mobile:" + user.Mobile} {
+		result, err := c.RateLimitStore.Take(key, c.SMSSendLimit)
+		if err != nil {
+			respondError(w, rateLimitUnavailable(err))
+			return false
+		}
+		if !result.Allowed {
+			seconds := ceilSeconds(result.RetryAfter)
+			w.Header().Set(headerRetryAfter, strconv.Itoa(seconds))
+			respondError(w, newHTTPError(http.StatusTooManyRequests,
+				"too many codes sent; please try again in %d seconds", seconds))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/davestearns/userservice/models/clients"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//clientView is the view of a registered client returned to its owner
+type clientView struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectURIs"`
+	Public       bool      `json:"public"`
+	Created      time.Time `json:"created"`
+	//Secret is returned only when the client is registered
+	Secret string `json:"secret,omitempty"`
+}
+
+//newClientView constructs a new clientView
+func newClientView(client *clients.Client, secret string) *clientView {
+	return &clientView{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.Public(),
+		Created:      client.Created,
+		Secret:       secret,
+	}
+}
+
+//OAuthClientsHandler handles requests for the /oauth/clients resource
+func (c *Config) OAuthClientsHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		if err := authorizeService(sessionState, users.PermRegisterClient); err != nil {
+			respondError(w, err)
+			return
+		}
+		reg := &clients.Registration{}
+		if err := receive(r, reg); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted client registration: %v", err))
+			return
+		}
+		secret, client, err := reg.ToClient(sessionState.User.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.ClientStore.Insert(client); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.Header().Add(headerLocation, "/oauth/clients/"+client.ID)
+		respond(w, newClientView(client, secret), http.StatusCreated)
+
+	case http.MethodGet:
+		owned, err := c.ClientStore.List(sessionState.User.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		views := []*clientView{}
+		for _, client := range owned {
+			views = append(views, newClientView(client, ""))
+		}
+		respond(w, views, http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SpecificOAuthClientHandler handles requests for the /oauth/clients/<id> resource
+func (c *Config) SpecificOAuthClientHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	client, err := c.ClientStore.Get(path.Base(r.URL.Path))
+	//clients owned by other users are reported as not found
+	if err == clients.ErrNotFound || (err == nil && client.Owner != sessionState.User.UserName) {
+		respondError(w, newHTTPError(http.StatusNotFound, clients.ErrNotFound.Error()))
+		return
+	}
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		respond(w, newClientView(client, ""), http.StatusOK)
+
+	case http.MethodDelete:
+		if err := c.ClientStore.Delete(client.ID); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("client deleted"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
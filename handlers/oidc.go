@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davestearns/userservice/jwt"
+	"github.com/davestearns/userservice/models/authcodes"
+	"github.com/davestearns/userservice/models/clients"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//supportedScopes are the OpenID Connect scopes that clients may request
+var supportedScopes = []string{"openid", "profile", "email"}
+
+//authorizationRequest is an OAuth authorization request
+type authorizationRequest struct {
+	client        *clients.Client
+	redirectURI   string
+	scope         string
+	state         string
+	nonce         string
+	codeChallenge string
+}
+
+//authorizationResult is the response to an authorization request
+//made by the sign-in page on behalf of a signed-in user
+type authorizationResult struct {
+	//RedirectURI is the client's redirect URI with the authorization
+	//code or error appended, to which the user's browser should be sent
+	RedirectURI string `json:"redirectURI"`
+}
+
+//discoveryDocument is the OpenID Connect provider metadata
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+//userInfoResponse is the response from the userinfo endpoint
+type userInfoResponse struct {
+	Subject string `json:"sub"`
+	*users.StandardClaims
+}
+
+//AuthorizeHandler handles requests for the /oauth/authorize resource,
+//which is the OpenID Connect authorization endpoint. Browsers are sent
+//here by clients, and are redirected to the sign-in page with the same
+//query; once the user signs in, the sign-in page POSTs the query here
+//with the user's session to obtain the redirect back to the client.
+func (c *Config) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if _, ok := c.parseAuthorizationRequest(w, r); !ok {
+			return
+		}
+		signInURL := c.SignInURL
+		if strings.Contains(signInURL, "?") {
+			signInURL += "&" + r.URL.RawQuery
+		} else {
+			signInURL += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, signInURL, http.StatusFound)
+
+	case http.MethodPost:
+		c.EnsureSession(c.authorize)(w, r)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//authorize issues an authorization code for the signed-in user
+//and responds with the redirect back to the client
+func (c *Config) authorize(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	ar, ok := c.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+	plaintext, code, err := authcodes.NewCode(&authcodes.Code{
+		ClientID:      ar.client.ID,
+		UserName:      sessionState.User.UserName,
+		RedirectURI:   ar.redirectURI,
+		Scope:         ar.scope,
+		Nonce:         ar.nonce,
+		CodeChallenge: ar.codeChallenge,
+		AuthTime:      sessionState.Began,
+	}, c.AuthCodeTTL)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if err := c.AuthCodeStore.Insert(code); err != nil {
+		respondError(w, err)
+		return
+	}
+	respond(w, &authorizationResult{
+		RedirectURI: appendQuery(ar.redirectURI, url.Values{"code": {plaintext}, "state": {ar.state}}),
+	}, http.StatusOK)
+}
+
+//parseAuthorizationRequest parses and validates the authorization request
+//in the query or form. If the client or redirect URI is invalid, it
+//responds with an error, as the user must not be redirected to an
+//unregistered URI; otherwise errors are reported to the client by
+//redirecting to its redirect URI. It returns false if it responded.
+func (c *Config) parseAuthorizationRequest(w http.ResponseWriter, r *http.Request) (*authorizationRequest, bool) {
+	if err := r.ParseForm(); err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, "error parsing authorization request: %v", err))
+		return nil, false
+	}
+	client, err := c.ClientStore.Get(r.Form.Get("client_id"))
+	if err != nil {
+		if err == clients.ErrNotFound {
+			respondError(w, newHTTPError(http.StatusBadRequest, "unknown client_id"))
+			return nil, false
+		}
+		respondError(w, err)
+		return nil, false
+	}
+	redirectURI := r.Form.Get("redirect_uri")
+	if !client.AllowsRedirect(redirectURI) {
+		respondError(w, newHTTPError(http.StatusBadRequest, "redirect_uri is not registered for this client"))
+		return nil, false
+	}
+
+	ar := &authorizationRequest{
+		client:        client,
+		redirectURI:   redirectURI,
+		state:         r.Form.Get("state"),
+		nonce:         r.Form.Get("nonce"),
+		codeChallenge: r.Form.Get("code_challenge"),
+	}
+	redirectError := func(code string, description string) (*authorizationRequest, bool) {
+		errorURI := appendQuery(redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {ar.state},
+		})
+		//the sign-in page follows the redirect itself
+		if r.Method == http.MethodPost {
+			respond(w, &authorizationResult{RedirectURI: errorURI}, http.StatusOK)
+		} else {
+			http.Redirect(w, r, errorURI, http.StatusFound)
+		}
+		return nil, false
+	}
+	if r.Form.Get("response_type") != "code" {
+		return redirectError("unsupported_response_type", "only the code response type is supported")
+	}
+	if len(ar.codeChallenge) == 0 || r.Form.Get("code_challenge_method") != authcodes.MethodS256 {
+		return redirectError("invalid_request", "a PKCE code_challenge using the S256 method is required")
+	}
+	//unsupported scopes are ignored, but openid is required
+	var scopes []string
+	for _, scope := range strings.Fields(r.Form.Get("scope")) {
+		if containsString(supportedScopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if !containsString(scopes, "openid") {
+		return redirectError("invalid_scope", "the openid scope is required")
+	}
+	ar.scope = strings.Join(scopes, " ")
+	return ar, true
+}
+
+//authorizationCodeGrant issues tokens in exchange for an authorization code
+func (c *Config) authorizationCodeGrant(w http.ResponseWriter, r *http.Request) {
+	client, ok := c.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+	code, err := c.AuthCodeStore.Take(authcodes.HashCode(r.PostFormValue("code")))
+	if err != nil {
+		if err == authcodes.ErrNotFound {
+			respondOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+			return
+		}
+		respondError(w, err)
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectURI != r.PostFormValue("redirect_uri") ||
+		!code.VerifyChallenge(r.PostFormValue("code_verifier")) {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid authorization code, redirect_uri or code_verifier")
+		return
+	}
+
+	user, err := c.UserStore.Get(code.UserName)
+	if err != nil && !errors.Is(err, users.ErrNotFound) {
+		respondError(w, err)
+		return
+	}
+	if err != nil || user.CredentialsChanged.After(code.AuthTime) {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", authcodes.ErrNotFound.Error())
+		return
+	}
+	if err := ensureActive(user); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+
+	scopes := strings.Fields(code.Scope)
+	now := time.Now()
+	accessToken, err := c.signAccessToken(user, code.AuthTime, client.ID, code.Scope, "")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	idToken, err := c.AccessTokenSigner.Sign(&idTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   c.TokenIssuer,
+			Subject:  user.UserName,
+			Audience: client.ID,
+			IssuedAt: now.Unix(),
+			Expires:  now.Add(c.AccessTokenTTL).Unix(),
+		},
+		AuthTime:       unixCeil(code.AuthTime),
+		Nonce:          code.Nonce,
+		StandardClaims: user.StandardClaims(scopes),
+	})
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Set(headerCacheControl, "no-store")
+	respond(w, &tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(c.AccessTokenTTL / time.Second),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, http.StatusOK)
+}
+
+//authenticateClient authenticates the client making a token request,
+//using either HTTP Basic authentication or the client_id and
+//client_secret form values. Public clients supply only a client_id.
+//If authentication fails, it responds and returns false.
+func (c *Config) authenticateClient(w http.ResponseWriter, r *http.Request) (*clients.Client, bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+	client, err := c.ClientStore.Get(clientID)
+	if err != nil && err != clients.ErrNotFound {
+		respondError(w, err)
+		return nil, false
+	}
+	if err != nil || (client.Public() && len(secret) > 0) || (!client.Public() && !client.Authenticate(secret)) {
+		if basic {
+			w.Header().Set(headerWWWAuthenticate, `Basic realm="token"`)
+		}
+		respondOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return nil, false
+	}
+	return client, true
+}
+
+//UserInfoHandler handles requests for the /oauth/userinfo resource, which
+//returns the standard claims permitted by the bearer access token's scope
+func (c *Config) UserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+		token, _ := bearerJWT(r)
+		claims := &accessTokenClaims{}
+		if err := c.AccessTokenSigner.Verify(token, claims); err != nil ||
+			claims.Issuer != c.TokenIssuer || len(claims.Audience) == 0 ||
+			!containsString(strings.Fields(claims.Scope), "openid") {
+			w.Header().Set(headerWWWAuthenticate, `Bearer error="invalid_token"`)
+			respondError(w, newHTTPError(http.StatusUnauthorized, "invalid access token"))
+			return
+		}
+		user, err := c.UserStore.Get(claims.Subject)
+		if err != nil && !errors.Is(err, users.ErrNotFound) {
+			respondError(w, err)
+			return
+		}
+		if err != nil || user.CredentialsChanged.After(time.Unix(claims.AuthTime, 0)) {
+			w.Header().Set(headerWWWAuthenticate, `Bearer error="invalid_token"`)
+			respondError(w, newHTTPError(http.StatusUnauthorized, "invalid access token"))
+			return
+		}
+		if err := ensureActive(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, &userInfoResponse{
+			Subject:        user.UserName,
+			StandardClaims: user.StandardClaims(strings.Fields(claims.Scope)),
+		}, http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//DiscoveryHandler handles requests for the OpenID Connect
+//discovery document at /.well-known/openid-configuration
+func (c *Config) DiscoveryHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		issuer := strings.TrimSuffix(c.TokenIssuer, "/")
+		respond(w, &discoveryDocument{
+			Issuer:                            c.TokenIssuer,
+			AuthorizationEndpoint:             issuer + "/oauth/authorize",
+			TokenEndpoint:                     issuer + "/tokens",
+			UserInfoEndpoint:                  issuer + "/oauth/userinfo",
+			JWKSURI:                           issuer + "/.well-known/jwks.json",
+			RegistrationEndpoint:              issuer + "/oauth/clients",
+			ScopesSupported:                   supportedScopes,
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               []string{"authorization_code"},
+			SubjectTypesSupported:             []string{"public"},
+			IDTokenSigningAlgValuesSupported:  []string{c.AccessTokenSigner.Keys()[0].Algorithm},
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+			CodeChallengeMethodsSupported:     []string{authcodes.MethodS256},
+			ClaimsSupported: []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+				"preferred_username", "name", "given_name", "family_name", "email", "email_verified"},
+		}, http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//JWKSHandler handles requests for the /.well-known/jwks.json resource,
+//which publishes the public keys that verify access and ID tokens
+func (c *Config) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		respond(w, c.AccessTokenSigner.JWKSet(), http.StatusOK)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//appendQuery appends params to the query of uri
+func appendQuery(uri string, params url.Values) string {
+	for name, values := range params {
+		if len(values) == 0 || len(values[0]) == 0 {
+			delete(params, name)
+		}
+	}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + params.Encode()
+	}
+	return uri + "?" + params.Encode()
+}
+
+//containsString returns true if s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/davestearns/userservice/models/challenges"
+	"github.com/davestearns/userservice/models/users"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+//passkeyOptions is returned when a passkey ceremony begins. The PublicKey
+//options should be passed to navigator.credentials.create() or .get(),
+//and the ChallengeID sent back along with the resulting credential.
+type passkeyOptions struct {
+	ChallengeID string      `json:"challengeID"`
+	PublicKey   interface{} `json:"publicKey"`
+}
+
+//passkeyView is the view of a registered passkey returned to its owner
+type passkeyView struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	BackedUp bool      `json:"backedUp"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+//webauthnUser adapts a users.User to the webauthn.User interface.
+//The user's random WebAuthn handle is used as the user handle, so that
+//discoverable credentials identify their user without revealing the userName.
+type webauthnUser struct {
+	*users.User
+}
+
+func (wu *webauthnUser) WebAuthnID() []byte {
+	return wu.WebAuthnUserHandle()
+}
+
+func (wu *webauthnUser) WebAuthnName() string {
+	return wu.UserName
+}
+
+func (wu *webauthnUser) WebAuthnDisplayName() string {
+	if len(wu.PersonalName) > 0 || len(wu.FamilyName) > 0 {
+		return wu.PersonalName + " " + wu.FamilyName
+	}
+	return wu.UserName
+}
+
+func (wu *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (wu *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	var creds []webauthn.Credential
+	for _, p := range wu.Passkeys {
+		var transports []protocol.AuthenticatorTransport
+		for _, t := range p.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+		creds = append(creds, webauthn.Credential{
+			ID:              p.ID,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: p.BackupEligible,
+				BackupState:    p.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    p.AAGUID,
+				SignCount: p.SignCount,
+			},
+		})
+	}
+	return creds
+}
+
+//PasskeysHandler handles requests for the /users/me/passkeys resource
+func (c *Config) PasskeysHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	user := sessionState.User
+	switch r.Method {
+	case http.MethodGet:
+		views := []*passkeyView{}
+		for _, p := range user.Passkeys {
+			views = append(views, &passkeyView{
+				ID:       base64.RawURLEncoding.EncodeToString(p.ID),
+				Name:     p.Name,
+				BackedUp: p.BackupState,
+				Created:  p.Created,
+				LastUsed: p.LastUsed,
+			})
+		}
+		respond(w, views, http.StatusOK)
+
+	case http.MethodPost:
+		//complete a registration begun at /users/me/passkeys/challenges
+		registration := &users.PasskeyRegistration{}
+		if err := receive(r, registration); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted registration: %v", err))
+			return
+		}
+		session := &webauthn.SessionData{}
+		if err := c.takeChallenge(registration.ChallengeID, user.UserName, session); err != nil {
+			respondError(w, err)
+			return
+		}
+		parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(registration.Credential))
+		if err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "invalid credential: %v", err))
+			return
+		}
+		cred, err := c.WebAuthn.CreateCredential(&webauthnUser{user}, *session, parsed)
+		if err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "invalid credential: %v", err))
+			return
+		}
+		var transports []string
+		for _, t := range cred.Transport {
+			transports = append(transports, string(t))
+		}
+		passkey := &users.Passkey{
+			ID:              cred.ID,
+			Name:            registration.Name,
+			PublicKey:       cred.PublicKey,
+			AttestationType: cred.AttestationType,
+			Transports:      transports,
+			AAGUID:          cred.Authenticator.AAGUID,
+			SignCount:       cred.Authenticator.SignCount,
+			BackupEligible:  cred.Flags.BackupEligible,
+			BackupState:     cred.Flags.BackupState,
+			Created:         time.Now().UTC(),
+		}
+		if err := user.AddPasskey(passkey); err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, &passkeyView{
+			ID:       base64.RawURLEncoding.EncodeToString(passkey.ID),
+			Name:     passkey.Name,
+			BackedUp: passkey.BackupState,
+			Created:  passkey.Created,
+		}, http.StatusCreated)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//PasskeyChallengesHandler handles requests for the /users/me/passkeys/challenges resource
+func (c *Config) PasskeyChallengesHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPost:
+		//begin registering a new passkey
+		if err := ensureNotDirectory(sessionState.User); err != nil {
+			respondError(w, err)
+			return
+		}
+		//the handle is saved before the ceremony begins, as the new passkey will carry it
+		generated, err := sessionState.User.EnsureWebAuthnHandle()
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if generated {
+			if err := c.UserStore.Save(sessionState.User); err != nil {
+				respondError(w, err)
+				return
+			}
+		}
+		wu := &webauthnUser{sessionState.User}
+		var exclusions []protocol.CredentialDescriptor
+		for _, cred := range wu.WebAuthnCredentials() {
+			exclusions = append(exclusions, cred.Descriptor())
+		}
+		creation, session, err := c.WebAuthn.BeginRegistration(wu,
+			webauthn.WithExclusions(exclusions),
+			webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+				RequireResidentKey: protocol.ResidentKeyRequired(),
+				ResidentKey:        protocol.ResidentKeyRequirementRequired,
+				UserVerification:   protocol.VerificationRequired,
+			}))
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		c.respondPasskeyOptions(w, wu.UserName, session, creation.Response)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SpecificPasskeyHandler handles requests for the /users/me/passkeys/<id> resource
+func (c *Config) SpecificPasskeyHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodDelete:
+		id, err := base64.RawURLEncoding.DecodeString(path.Base(r.URL.Path))
+		if err != nil {
+			respondError(w, users.ErrPasskeyNotFound)
+			return
+		}
+		user := sessionState.User
+		if err := user.RemovePasskey(id); err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("passkey revoked"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SessionsPasskeyChallengesHandler handles requests for the /sessions/passkey/challenges resource
+func (c *Config) SessionsPasskeyChallengesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		//begin a discoverable sign-in, so the client doesn't need to supply a userName
+		assertion, session, err := c.WebAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		c.respondPasskeyOptions(w, "", session, assertion.Response)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SessionsPasskeyHandler handles requests for the /sessions/passkey resource
+func (c *Config) SessionsPasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		assertion := &users.PasskeyAssertion{}
+		if err := receive(r, assertion); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted assertion: %v", err))
+			return
+		}
+		session := &webauthn.SessionData{}
+		if err := c.takeChallenge(assertion.ChallengeID, "", session); err != nil {
+			respondError(w, err)
+			return
+		}
+		parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(assertion.Credential))
+		if err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "invalid credential: %v", err))
+			return
+		}
+
+		var user *users.User
+		var lookupErr error
+		cred, err := c.WebAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			user, lookupErr = c.passkeyUser(userHandle)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			return &webauthnUser{user}, nil
+		}, *session, parsed)
+		if lookupErr != nil && !errors.Is(lookupErr, users.ErrNotFound) {
+			respondError(w, lookupErr)
+			return
+		}
+		if err != nil || cred.Authenticator.CloneWarning {
+			respondError(w, newHTTPError(http.StatusUnauthorized, invalidCredentials))
+			return
+		}
+
+		passkey, err := user.FindPasskey(cred.ID)
+		if err != nil {
+			respondError(w, newHTTPError(http.StatusUnauthorized, invalidCredentials))
+			return
+		}
+		if err := ensureNotDirectory(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		passkey.SignCount = cred.Authenticator.SignCount
+		passkey.BackupState = cred.Flags.BackupState
+		passkey.LastUsed = time.Now().UTC()
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+		c.beginSession(w, r, user, assertion.RememberMe)
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//passkeyUser returns the user identified by a passkey's user handle.
+//Passkeys registered before handles were generated carry the user name
+//instead, which identifies only accounts that have no generated handle.
+func (c *Config) passkeyUser(handle []byte) (*users.User, error) {
+	user, err := c.UserStore.GetByWebAuthnHandle(handle)
+	if !errors.Is(err, users.ErrNotFound) {
+		return user, err
+	}
+	user, err = c.UserStore.Get(string(handle))
+	if err != nil {
+		return nil, err
+	}
+	if len(user.WebAuthnHandle) > 0 {
+		return nil, users.ErrNotFound
+	}
+	return user, nil
+}
+
+//respondPasskeyOptions stores the WebAuthn session data for the
+//ceremony and responds with the options for the client
+func (c *Config) respondPasskeyOptions(w http.ResponseWriter, userName string, session *webauthn.SessionData, options interface{}) {
+	id, challenge, err := challenges.NewChallenge(userName, session, c.PasskeyChallengeTTL)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if err := c.ChallengeStore.Insert(challenge); err != nil {
+		respondError(w, err)
+		return
+	}
+	respond(w, &passkeyOptions{ChallengeID: id, PublicKey: options}, http.StatusCreated)
+}
+
+//takeChallenge takes the challenge with the plaintext id, ensuring that it
+//was begun by userName, and decodes its WebAuthn session data into session
+func (c *Config) takeChallenge(id string, userName string, session *webauthn.SessionData) error {
+	challenge, err := c.ChallengeStore.Take(challenges.HashID(id))
+	if err != nil {
+		if err == challenges.ErrNotFound {
+			return newHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+	if challenge.UserName != userName {
+		return newHTTPError(http.StatusBadRequest, challenges.ErrNotFound.Error())
+	}
+	return challenge.Decode(session)
+}
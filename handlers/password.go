@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//PasswordHandler handles requests for the /users/me/password resource
+func (c *Config) PasswordHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodPut:
+		change := &users.PasswordChange{}
+		if err := receive(r, change); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted password change: %v", err))
+			return
+		}
+		user, err := c.UserStore.Get(sessionState.User.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := user.ChangePassword(change.CurrentPassword, change.NewPassword); err != nil {
+			if err == users.ErrInvalidPassword {
+				respondError(w, newHTTPError(http.StatusForbidden, err.Error()))
+				return
+			}
+			respondError(w, newHTTPError(http.StatusBadRequest, "error changing password: %v", err))
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			respondError(w, err)
+			return
+		}
+
+		//all sessions and tokens that began before the change are now
+		//invalid, so end them; a session is replaced with a new one, while
+		//a token client must sign in again, so its refresh family is revoked
+		if sessionState.fromToken() {
+			if err := c.endAllSessions(user.UserName); err != nil {
+				log.Printf("error ending sessions of '%s' after password change: %v", user.UserName, err)
+			}
+			if len(sessionState.TokenFamily) > 0 {
+				if err := c.RefreshTokenStore.RevokeFamily(sessionState.TokenFamily); err != nil {
+					log.Printf("error revoking refresh tokens of '%s' after password change: %v", user.UserName, err)
+				}
+			}
+			w.Write([]byte("password changed"))
+			return
+		}
+		c.SessionManager.EndSession(r)
+		if err := c.endAllSessions(user.UserName); err != nil {
+			log.Printf("error ending sessions of '%s' after password change: %v", user.UserName, err)
+		}
+		if err := c.startSession(w, r, user, sessionState.Remember); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("password changed"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
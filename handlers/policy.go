@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davestearns/userservice/models/users"
+)
+
+//This file is the single place where requests are forbidden: handlers
+//call these functions rather than checking roles or user names inline.
+
+//TargetedHandlerFunc is a StatefulHandlerFunc that acts on the account of target
+type TargetedHandlerFunc func(w http.ResponseWriter, r *http.Request, sessionState *SessionState, target *users.User)
+
+//EnsurePermission is an adapter built on EnsureSession that converts a
+//TargetedHandlerFunc into an http.HandlerFunc. The signed-in user must
+//have perm on the account of targetUserName.
+func (c *Config) EnsurePermission(perm users.Permission, targetUserName string, handlerFunc TargetedHandlerFunc) http.HandlerFunc {
+	return c.EnsureSession(func(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+		target, err := c.authorizedTarget(sessionState, perm, targetUserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		handlerFunc(w, r, sessionState, target)
+	})
+}
+
+//authorizedTarget returns the account of targetUserName if the signed-in
+//user has perm on it, or else a 403 error
+func (c *Config) authorizedTarget(sessionState *SessionState, perm users.Permission, targetUserName string) (*users.User, error) {
+	target := sessionState.User
+	if targetUserName != target.UserName {
+		var err error
+		if target, err = c.UserStore.Get(targetUserName); err != nil {
+			return nil, err
+		}
+	}
+	if err := authorize(sessionState, perm, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+//authorize returns a 403 error unless the signed-in user has perm on the account of target
+func authorize(sessionState *SessionState, perm users.Permission, target *users.User) error {
+	if !sessionState.User.Can(perm, target) {
+		return newHTTPError(http.StatusForbidden, "you don't have the %s permission for '%s'", perm, target.UserName)
+	}
+	return nil
+}
+
+//authorizeService returns a 403 error unless the signed-in user's
+//role grants perm, which doesn't act on any account
+func authorizeService(sessionState *SessionState, perm users.Permission) error {
+	if !sessionState.User.Has(perm) {
+		return newHTTPError(http.StatusForbidden, "you don't have the %s permission", perm)
+	}
+	return nil
+}
+
+//ensureNotDirectory returns a 403 error if user's account was provisioned
+//from an LDAP directory, which alone may sign the user in
+func ensureNotDirectory(user *users.User) error {
+	if user.Directory {
+		return newHTTPError(http.StatusForbidden, "your account is managed by your organization's directory; please sign in with your directory password")
+	}
+	return nil
+}
+
+//ensureActive returns a 403 error unless user's account is active
+func ensureActive(user *users.User) error {
+	switch user.EffectiveStatus() {
+	case users.StatusActive:
+		return nil
+	case users.StatusPendingDeletion:
+		return newHTTPError(http.StatusForbidden,
+			"your account is scheduled for deletion; sign in at /account-restorations to restore it")
+	default:
+		return newHTTPError(http.StatusForbidden, "your account has been suspended")
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//RateLimit is middleware that applies the first of c.RateLimitRules matching
+//each request, using a separate token bucket for each rule and client IP
+//address. Clients aren't identified by session, as resolving one costs
+//store reads that would themselves be unlimited.
+func (c *Config) RateLimit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range c.RateLimitRules {
+			if !rule.Matches(r) {
+				continue
+			}
+			result, err := c.RateLimitStore.Take(rule.String()+" ip:"+clientIP(r), rule.Limit)
+			if err != nil {
+				respondError(w, rateLimitUnavailable(err))
+				return
+			}
+			w.Header().Set(headerRateLimitLimit, strconv.Itoa(result.Limit))
+			w.Header().Set(headerRateLimitRemaining, strconv.Itoa(result.Remaining))
+			w.Header().Set(headerRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				seconds := ceilSeconds(result.RetryAfter)
+				w.Header().Set(headerRetryAfter, strconv.Itoa(seconds))
+				respondError(w, newHTTPError(http.StatusTooManyRequests,
+					"too many requests; please try again in %d seconds", seconds))
+				return
+			}
+			break
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+//ceilSeconds returns d in whole seconds, rounded up
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
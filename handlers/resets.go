@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/davestearns/userservice/mailer"
+	"github.com/davestearns/userservice/models/resets"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//PasswordResetsHandler handles requests for the /password-resets resource
+func (c *Config) PasswordResetsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		req := &resets.Request{}
+		if err := receive(r, req); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted reset request: %v", err))
+			return
+		}
+		//respond the same way whether or not the account exists,
+		//so that this can't be used to discover account names or emails
+		if err := c.sendResetToken(req); err != nil && !errors.Is(err, users.ErrNotFound) {
+			respondError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("if that account exists, a password reset message has been sent to its email address"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//SpecificPasswordResetHandler handles requests for the /password-resets/<token> resource
+func (c *Config) SpecificPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		reset := &resets.Reset{}
+		if err := receive(r, reset); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted reset: %v", err))
+			return
+		}
+		token, err := c.ResetStore.Take(resets.HashToken(path.Base(r.URL.Path)))
+		if err != nil {
+			if err == resets.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusNotFound, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		user, err := c.UserStore.Get(token.UserName)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := user.SetPassword(reset.Password); err != nil {
+			//put the token back so the user can try again with a better password
+			if err := c.ResetStore.Insert(token); err != nil {
+				log.Printf("error re-inserting reset token for '%s': %v", token.UserName, err)
+			}
+			respondError(w, newHTTPError(http.StatusBadRequest, "error resetting password: %v", err))
+			return
+		}
+		if err := c.UserStore.Save(user); err != nil {
+			//put the token back so the user can try again
+			if err := c.ResetStore.Insert(token); err != nil {
+				log.Printf("error re-inserting reset token for '%s': %v", token.UserName, err)
+			}
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("password reset; please sign in with your new password"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//sendResetToken generates and stores a new reset token for the
+//account identified by req, and emails it to the account's address.
+//Only errors finding the account are returned: those that occur once it
+//has been found are logged, as reporting them would reveal that it exists.
+func (c *Config) sendResetToken(req *resets.Request) error {
+	var user *users.User
+	var err error
+	if len(req.UserName) > 0 {
+		user, err = c.UserStore.Get(req.UserName)
+	} else {
+		user, err = c.UserStore.GetByVerifiedEmail(req.Email)
+	}
+	if err != nil {
+		return err
+	}
+	//only verified addresses are sent resets, as an unverified one
+	//may belong to someone other than the account's owner
+	if len(user.Email) == 0 || !user.EmailVerified {
+		return users.ErrNotFound
+	}
+
+	plaintext, token, err := resets.NewToken(user.UserName, c.ResetTTL)
+	if err != nil {
+		log.Printf("error generating reset token for '%s': %v", user.UserName, err)
+		return nil
+	}
+	if err := c.ResetStore.Insert(token); err != nil {
+		log.Printf("error storing reset token for '%s': %v", user.UserName, err)
+		return nil
+	}
+	err = c.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone (hopefully you) asked to reset the password for the account '%s'.\n\n"+
+			"To choose a new password, visit the following link within %v:\n\n%s%s\n\n"+
+			"If you didn't ask to reset your password, you can ignore this message.",
+			user.UserName, c.ResetTTL, c.ResetURL, plaintext),
+	})
+	if err != nil {
+		log.Printf("error sending reset message to '%s': %v", user.UserName, err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"time"
+)
+
+//renewInterval is the minimum time between saves of a session's
+//last activity, so that active sessions aren't saved on every request
+const renewInterval = time.Minute
+
+//SessionLifetime determines how long a session lasts
+type SessionLifetime struct {
+	//IdleTimeout is how long a session may go unused before it expires;
+	//each request renews the session
+	IdleTimeout time.Duration
+	//MaxAge is how long a session may last after it began,
+	//regardless of activity
+	MaxAge time.Duration
+}
+
+//expired returns true if the session state has expired at now
+func (sl SessionLifetime) expired(sessionState *SessionState, now time.Time) bool {
+	return now.Sub(sessionState.LastActive) > sl.IdleTimeout ||
+		now.Sub(sessionState.Began) > sl.MaxAge
+}
+
+//sessionLifetime returns the lifetime that applies to the session state
+func (c *Config) sessionLifetime(sessionState *SessionState) SessionLifetime {
+	if sessionState.Remember {
+		return c.RememberedSessionLifetime
+	}
+	return c.SessionLifetime
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 
+	"github.com/davestearns/userservice/authn"
 	"github.com/davestearns/userservice/models/users"
+	"github.com/davestearns/userservice/models/usersessions"
 )
 
 const invalidCredentials = "invalid credentials"
@@ -17,48 +17,80 @@ func (c *Config) SessionsHandler(w http.ResponseWriter, r *http.Request) {
 		//sign-in
 		creds := &users.Credentials{}
 		if err := receive(r, creds); err != nil {
-			http.Error(w, fmt.Sprintf("error receiving posted credentials: %v", err), http.StatusBadRequest)
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted credentials: %v", err))
 			return
 		}
-		//get the user associated with that user name
-		user, err := c.UserStore.Get(creds.UserName)
-		if err != nil {
-			log.Printf("error getting user '%s' from user store: %v", creds.UserName, err)
-			users.DummyAuthenticate()
-			http.Error(w, invalidCredentials, http.StatusUnauthorized)
+		if !c.ensureNotLockedOut(w, r, creds.UserName) {
 			return
 		}
-
-		if err := user.Authenticate([]byte(creds.Password)); err != nil {
-			http.Error(w, invalidCredentials, http.StatusUnauthorized)
+		user, err := c.Authenticator.Authenticate(creds.UserName, creds.Password)
+		if err != nil {
+			if err == authn.ErrInvalidCredentials {
+				c.respondSignInFailure(w, r, creds.UserName, invalidCredentials)
+				return
+			}
+			respondError(w, err)
 			return
 		}
 
-		if _, err := c.SessionManager.BeginSession(w, NewSessionState(r, user)); err != nil {
-			http.Error(w, fmt.Sprintf("error starting new session: %v", err), http.StatusInternalServerError)
+		//users with two-factor authentication must
+		//complete a challenge before a session begins
+		if user.TOTPEnabled {
+			c.respondMFAChallenge(w, user, creds.RememberMe, false)
 			return
 		}
-		w.Header().Add(headerLocation, "/sessions/mine")
-		respond(w, user, http.StatusCreated)
+		c.beginSession(w, r, user, creds.RememberMe)
+
+	case http.MethodGet:
+		c.EnsureSession(c.listSessions)(w, r)
+
+	case http.MethodDelete:
+		//sign out everywhere else
+		c.EnsureSession(c.endOtherSessions)(w, r)
 
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		respondError(w, errMethodNotAllowed)
 		return
 	}
 }
 
+//beginSession begins a new session for the fully-authenticated
+//user and writes the sign-in response
+func (c *Config) beginSession(w http.ResponseWriter, r *http.Request, user *users.User, remember bool) {
+	if err := ensureActive(user); err != nil {
+		respondError(w, err)
+		return
+	}
+	//a successful sign-in clears the failures recorded against the user name
+	if err := c.UserThrottler.Reset(user.UserName); err != nil {
+		respondError(w, throttleUnavailable(err))
+		return
+	}
+	if err := c.startSession(w, r, user, remember); err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Add(headerLocation, "/sessions/mine")
+	respond(w, user.Private(), http.StatusCreated)
+}
+
 //SessionsMineHandler handles requests for the /sessions/mine resource
 func (c *Config) SessionsMineHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodDelete:
+		sessionState := &SessionState{}
+		sid, stateErr := c.SessionManager.GetState(r, sessionState)
 		if err := c.SessionManager.EndSession(r); err != nil {
-			http.Error(w, fmt.Sprintf("error ending session: %v", err), http.StatusInternalServerError)
+			respondError(w, err)
 			return
 		}
+		if stateErr == nil {
+			c.SessionIndex.Remove(sessionState.UserName, usersessions.PublicID(string(sid)))
+		}
 		w.Write([]byte("session ended"))
 
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		respondError(w, errMethodNotAllowed)
 		return
 	}
 }
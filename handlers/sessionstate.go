@@ -1,31 +1,84 @@
 package handlers
 
 import (
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/davestearns/sessions"
 	"github.com/davestearns/userservice/models/users"
 )
 
-//SessionState represents the state of a session
+//SessionState represents the state of a session. Only the user's
+//identity and the version of the user last seen by the session are
+//stored; the user itself is re-resolved by EnsureSession on each request.
 type SessionState struct {
+	//ID is the ID of the session, which is set by EnsureSession
+	//but not stored as part of the state
+	ID           sessions.SessionID `json:"-"`
 	Began        time.Time
 	ClientIPPath string
-	User         *users.User
+	UserName     string
+	UserVersion  int
+	//LastActive is when the session was last used,
+	//which EnsureSession renews on activity
+	LastActive time.Time
+	//Remember is true if the user asked to be remembered
+	//when signing in, which selects a longer session lifetime
+	Remember bool
+	//User is the current user record, which is set by EnsureSession
+	//but not stored as part of the state
+	User *users.User `json:"-"`
+	//TokenFamily is the refresh token family of the access token the
+	//request was authenticated with, which is set by requestState
+	//but not stored as part of the state
+	TokenFamily string `json:"-"`
+}
+
+//fromToken returns true if the state came from a bearer access token
+//rather than a session, in which case there's no session to end or renew
+func (ss *SessionState) fromToken() bool {
+	return len(ss.ID) == 0
 }
 
 //NewSessionState constructs a new SessionState
-func NewSessionState(r *http.Request, user *users.User) *SessionState {
-	var ipPath string
+func NewSessionState(r *http.Request, user *users.User, remember bool) *SessionState {
+	now := time.Now()
+	return &SessionState{
+		Began:        now,
+		ClientIPPath: clientIPPath(r),
+		LastActive:   now,
+		Remember:     remember,
+		UserName:     user.UserName,
+		UserVersion:  user.Version,
+		User:         user,
+	}
+}
+
+//clientIPPath returns the X-Forwarded-For header, if any,
+//followed by the address of the connecting client
+func clientIPPath(r *http.Request) string {
 	forwardedFor := r.Header.Get("X-Forwarded-For")
 	if len(forwardedFor) > 0 {
-		ipPath = forwardedFor + " " + r.RemoteAddr
-	} else {
-		ipPath = r.RemoteAddr
+		return forwardedFor + " " + r.RemoteAddr
 	}
-	return &SessionState{
-		Began:        time.Now(),
-		ClientIPPath: ipPath,
-		User:         user,
+	return r.RemoteAddr
+}
+
+//clientIP returns the IP address of the client. If the request has an
+//X-Forwarded-For header, this is the last address in it, which was added
+//by our load balancer; earlier addresses can be forged by the client.
+//Otherwise it's the address of the connecting client, without the port.
+func clientIP(r *http.Request) string {
+	forwardedFor := r.Header.Get("X-Forwarded-For")
+	if len(forwardedFor) > 0 {
+		addrs := strings.Split(forwardedFor, ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
@@ -1,20 +1,82 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"time"
+
+	"github.com/davestearns/userservice/models/users"
 )
 
 //StatefulHandlerFunc is an HTTP handler function that requires session state
 type StatefulHandlerFunc func(http.ResponseWriter, *http.Request, *SessionState)
 
-//EnsureSession is an adapter that converts a StatefulHandlerFunc into an http.HandlerFunc
+//EnsureSession is an adapter that converts a StatefulHandlerFunc into an http.HandlerFunc.
+//The request must carry either a session ID or a valid bearer JWT access token.
 func (c *Config) EnsureSession(handlerFunc StatefulHandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionState := &SessionState{}
-		if _, err := c.SessionManager.GetState(r, sessionState); err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		sessionState, err := c.requestState(r)
+		if err != nil {
+			respondError(w, newHTTPError(http.StatusUnauthorized, "please sign in"))
+			return
+		}
+		isSession := !sessionState.fromToken()
+
+		now := time.Now()
+		if isSession && c.sessionLifetime(sessionState).expired(sessionState, now) {
+			c.endCurrentSession(r, sessionState)
+			respondError(w, newHTTPError(http.StatusUnauthorized, "your session has expired; please sign in again"))
+			return
+		}
+
+		//sessions that began before the user's credentials
+		//last changed are no longer valid
+		user, err := c.UserStore.Get(sessionState.UserName)
+		if err != nil && !errors.Is(err, users.ErrNotFound) {
+			respondError(w, err)
+			return
+		}
+		if err != nil || user.CredentialsChanged.After(sessionState.Began) {
+			if isSession {
+				c.endCurrentSession(r, sessionState)
+			}
+			respondError(w, newHTTPError(http.StatusUnauthorized, "your session has expired; please sign in again"))
+			return
+		}
+		if err := ensureActive(user); err != nil {
+			if isSession {
+				c.endCurrentSession(r, sessionState)
+			}
+			respondError(w, err)
 			return
 		}
+		//renew the session and record the version of a user whose profile
+		//has changed since the state was last saved, so the stored session
+		//tracks the profile
+		if isSession && (now.Sub(sessionState.LastActive) > renewInterval || user.Version != sessionState.UserVersion) {
+			sessionState.LastActive = now
+			sessionState.UserVersion = user.Version
+			if err := c.SessionStore.Save(sessionState.ID, sessionState); err != nil {
+				log.Printf("error updating session state for %s: %v", user.UserName, err)
+			}
+		}
+		sessionState.User = user
 		handlerFunc(w, r, sessionState)
 	}
 }
+
+//requestState returns the state of the session identified by the request,
+//or the equivalent state for the request's bearer JWT access token
+func (c *Config) requestState(r *http.Request) (*SessionState, error) {
+	if token, ok := bearerJWT(r); ok {
+		return c.accessTokenState(r, token)
+	}
+	sessionState := &SessionState{}
+	sid, err := c.SessionManager.GetState(r, sessionState)
+	if err != nil {
+		return nil, err
+	}
+	sessionState.ID = sid
+	return sessionState, nil
+}
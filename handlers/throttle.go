@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//signInLockout returns how long the client must wait before attempting to
+//sign in as userName, which is the longer of the lockouts for that user
+//name and for the client's IP address, or zero if neither is locked out
+func (c *Config) signInLockout(r *http.Request, userName string) (time.Duration, error) {
+	userWait, err := c.UserThrottler.Check(userName)
+	if err != nil {
+		return 0, throttleUnavailable(err)
+	}
+	ipWait, err := c.IPThrottler.Check(clientIP(r))
+	if err != nil {
+		return 0, throttleUnavailable(err)
+	}
+	if ipWait > userWait {
+		return ipWait, nil
+	}
+	return userWait, nil
+}
+
+//recordSignInFailure records a failed attempt to sign in as
+//userName against both the user name and the client's IP address
+func (c *Config) recordSignInFailure(r *http.Request, userName string) error {
+	if _, err := c.UserThrottler.Fail(userName); err != nil {
+		return throttleUnavailable(err)
+	}
+	if _, err := c.IPThrottler.Fail(clientIP(r)); err != nil {
+		return throttleUnavailable(err)
+	}
+	return nil
+}
+
+//respondSignInFailure records a failed sign-in attempt and responds with
+//a 401 containing message. The IP address failures are not reset on a
+//successful sign-in, so an attacker can't clear them by signing in
+//to their own account between guesses.
+func (c *Config) respondSignInFailure(w http.ResponseWriter, r *http.Request, userName string, message string) {
+	if err := c.recordSignInFailure(r, userName); err != nil {
+		respondError(w, err)
+		return
+	}
+	respondError(w, newHTTPError(http.StatusUnauthorized, message))
+}
+
+//ensureNotLockedOut responds with a 429 and returns false if the client
+//is locked out of signing in as userName
+func (c *Config) ensureNotLockedOut(w http.ResponseWriter, r *http.Request, userName string) bool {
+	retryAfter, err := c.signInLockout(r, userName)
+	if err != nil {
+		respondError(w, err)
+		return false
+	}
+	if retryAfter > 0 {
+		seconds := ceilSeconds(retryAfter)
+		w.Header().Set(headerRetryAfter, strconv.Itoa(seconds))
+		respondError(w, newHTTPError(http.StatusTooManyRequests,
+			"too many failed sign-in attempts; please try again in %d seconds", seconds))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davestearns/userservice/authn"
+	"github.com/davestearns/userservice/jwt"
+	"github.com/davestearns/userservice/models/refreshtokens"
+	"github.com/davestearns/userservice/models/users"
+)
+
+//accessTokenClaims are the claims within a JWT access token
+type accessTokenClaims struct {
+	jwt.Claims
+	//AuthTime is when the user signed in, which is checked
+	//against when the user's credentials last changed
+	AuthTime int64 `json:"auth_time"`
+	//Scope is set for tokens issued to OpenID Connect clients,
+	//which have the client ID as their audience
+	Scope string `json:"scope,omitempty"`
+	//Family is the refresh token family issued along with the token,
+	//so that the family can be revoked by requests bearing the token
+	Family string `json:"sid,omitempty"`
+}
+
+//idTokenClaims are the claims within an OpenID Connect ID token
+type idTokenClaims struct {
+	jwt.Claims
+	AuthTime int64  `json:"auth_time"`
+	Nonce    string `json:"nonce,omitempty"`
+	*users.StandardClaims
+}
+
+//tokenResponse is the response from the token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+//oauthError is the body of an error response from the token endpoint,
+//in the form OAuth clients expect
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+//respondOAuthError writes an OAuth error response
+func respondOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set(headerCacheControl, "no-store")
+	respond(w, &oauthError{Error: code, Description: description}, status)
+}
+
+//TokensHandler handles requests for the /tokens resource, which is an
+//OAuth token endpoint that issues JWT access tokens and refresh tokens
+func (c *Config) TokensHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		switch grantType := r.PostFormValue("grant_type"); grantType {
+		case "password":
+			c.passwordGrant(w, r)
+		case "refresh_token":
+			c.refreshTokenGrant(w, r)
+		case "authorization_code":
+			c.authorizationCodeGrant(w, r)
+		default:
+			respondOAuthError(w, http.StatusBadRequest, "unsupported_grant_type",
+				fmt.Sprintf("grant type '%s' is not supported", grantType))
+		}
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//passwordGrant issues tokens in exchange for a user name and password,
+//plus a two-factor code if the user has enabled two-factor authentication
+func (c *Config) passwordGrant(w http.ResponseWriter, r *http.Request) {
+	userName := r.PostFormValue("username")
+	if !c.ensureNotLockedOut(w, r, userName) {
+		return
+	}
+	user, err := c.Authenticator.Authenticate(userName, r.PostFormValue("password"))
+	if err != nil {
+		if err == authn.ErrInvalidCredentials {
+			c.respondGrantFailure(w, r, userName, invalidCredentials)
+			return
+		}
+		respondError(w, err)
+		return
+	}
+
+	if user.TOTPEnabled {
+		code := r.PostFormValue("mfa_code")
+		if len(code) == 0 {
+			respondOAuthError(w, http.StatusBadRequest, "mfa_required", "a two-factor code is required")
+			return
+		}
+		if err := user.VerifyMFA(c.MFASealer, code); err != nil {
+			if err == users.ErrInvalidMFACode {
+				c.respondGrantFailure(w, r, userName, err.Error())
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		//saving records that the code was used; a version mismatch
+		//means the same code was used concurrently
+		if err := c.UserStore.Save(user); err != nil {
+			if errors.Is(err, users.ErrVersionMismatch) {
+				respondOAuthError(w, http.StatusBadRequest, "invalid_grant", users.ErrInvalidMFACode.Error())
+				return
+			}
+			respondError(w, err)
+			return
+		}
+	}
+
+	if err := c.UserThrottler.Reset(user.UserName); err != nil {
+		respondError(w, throttleUnavailable(err))
+		return
+	}
+	c.issueTokens(w, user, time.Now(), "")
+}
+
+//refreshTokenGrant issues new tokens in exchange for a refresh token,
+//which can't be used again
+func (c *Config) refreshTokenGrant(w http.ResponseWriter, r *http.Request) {
+	token, err := c.RefreshTokenStore.Take(refreshtokens.HashToken(r.PostFormValue("refresh_token")))
+	if err == refreshtokens.ErrReused {
+		//the token was stolen, or the legitimate client was
+		//issued a new one that was stolen, so revoke them all
+		log.Printf("refresh token reused for %s; revoking its family", token.UserName)
+		if err := c.RefreshTokenStore.RevokeFamily(token.Family); err != nil {
+			respondError(w, err)
+			return
+		}
+	}
+	if err != nil {
+		if err == refreshtokens.ErrNotFound || err == refreshtokens.ErrReused {
+			respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+			return
+		}
+		respondError(w, err)
+		return
+	}
+
+	//refresh tokens issued before the user's credentials last changed
+	//are no longer valid, and directory accounts may not use them
+	user, err := c.UserStore.Get(token.UserName)
+	if err != nil && !errors.Is(err, users.ErrNotFound) {
+		respondError(w, err)
+		return
+	}
+	if err != nil || user.CredentialsChanged.After(token.AuthTime) || user.Directory {
+		c.RefreshTokenStore.RevokeFamily(token.Family)
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+		return
+	}
+	c.issueTokens(w, user, token.AuthTime, token.Family)
+}
+
+//respondGrantFailure records a failed sign-in attempt
+//and responds with an OAuth invalid_grant error
+func (c *Config) respondGrantFailure(w http.ResponseWriter, r *http.Request, userName string, message string) {
+	if err := c.recordSignInFailure(r, userName); err != nil {
+		respondError(w, err)
+		return
+	}
+	respondOAuthError(w, http.StatusBadRequest, "invalid_grant", message)
+}
+
+//issueTokens responds with a new access token and a refresh token in
+//the given family, or in a new family if family is empty
+func (c *Config) issueTokens(w http.ResponseWriter, user *users.User, authTime time.Time, family string) {
+	if err := ensureActive(user); err != nil {
+		respondOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	//directory accounts must sign in via LDAP each time,
+	//so that the directory can disable them, and get no refresh token
+	refreshToken := ""
+	if !user.Directory {
+		plaintext, token, err := refreshtokens.NewToken(user.UserName, family, authTime, c.RefreshTokenTTL, c.RefreshFamilyMaxAge)
+		if err == refreshtokens.ErrFamilyExpired {
+			c.RefreshTokenStore.RevokeFamily(family)
+			respondOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+			return
+		}
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if err := c.RefreshTokenStore.Insert(token); err != nil {
+			respondError(w, err)
+			return
+		}
+		refreshToken, family = plaintext, token.Family
+	}
+	accessToken, err := c.signAccessToken(user, authTime, "", "", family)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Set(headerCacheControl, "no-store")
+	respond(w, &tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(c.AccessTokenTTL / time.Second),
+		RefreshToken: refreshToken,
+	}, http.StatusOK)
+}
+
+//signAccessToken returns a new JWT access token for user, who signed in at
+//authTime. Tokens for OpenID Connect clients have the client ID as their
+//audience and the granted scope; first-party tokens have neither, but
+//carry the family of the refresh token issued with them.
+func (c *Config) signAccessToken(user *users.User, authTime time.Time, audience string, scope string, family string) (string, error) {
+	now := time.Now()
+	return c.AccessTokenSigner.Sign(&accessTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   c.TokenIssuer,
+			Subject:  user.UserName,
+			Audience: audience,
+			IssuedAt: now.Unix(),
+			Expires:  now.Add(c.AccessTokenTTL).Unix(),
+		},
+		AuthTime: unixCeil(authTime),
+		Scope:    scope,
+		Family:   family,
+	})
+}
+
+//unixCeil returns t as Unix seconds, rounded up so that a change to the
+//user's credentials before t isn't seen as coming after it
+func unixCeil(t time.Time) int64 {
+	return int64(math.Ceil(float64(t.UnixNano()) / float64(time.Second)))
+}
+
+//bearerJWT returns the token from the request's Authorization header
+//and true if it's a JWT; session IDs never contain dots, but JWTs do
+func bearerJWT(r *http.Request) (string, bool) {
+	auth := r.Header.Get(headerAuthorization)
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	return token, strings.Count(token, ".") == 2
+}
+
+//accessTokenState verifies the JWT access token and returns
+//the equivalent session state
+func (c *Config) accessTokenState(r *http.Request, token string) (*SessionState, error) {
+	claims := &accessTokenClaims{}
+	if err := c.AccessTokenSigner.Verify(token, claims); err != nil {
+		return nil, err
+	}
+	//tokens issued to OpenID Connect clients are only for the userinfo endpoint
+	if claims.Issuer != c.TokenIssuer || len(claims.Subject) == 0 || len(claims.Audience) > 0 {
+		return nil, jwt.ErrInvalidToken
+	}
+	return &SessionState{
+		Began:        time.Unix(claims.AuthTime, 0),
+		ClientIPPath: clientIPPath(r),
+		UserName:     claims.Subject,
+		LastActive:   time.Unix(claims.IssuedAt, 0),
+		TokenFamily:  claims.Family,
+	}, nil
+}
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 	"net/url"
 	"path"
@@ -14,39 +15,43 @@ func (c *Config) UsersHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		//sign-up
-		newUser := &users.NewUser{}
-		if err := receive(r, newUser); err != nil {
-			http.Error(w, fmt.Sprintf("error receiving posted user: %v", err), http.StatusBadRequest)
-			return
-		}
-		existingUser, err := c.UserStore.Get(newUser.UserName)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("error checking for existing user with name '%s': %v", newUser.UserName, err), http.StatusInternalServerError)
+		if c.SignUpDisabled {
+			respondError(w, newHTTPError(http.StatusForbidden, "accounts can't be created here; sign in with your directory credentials"))
 			return
 		}
-		if existingUser != nil {
-			http.Error(w, fmt.Sprintf("sorry, but the user name '%s' is already taken", newUser.UserName), http.StatusInternalServerError)
+		newUser := &users.NewUser{}
+		if err := receive(r, newUser); err != nil {
+			respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted user: %v", err))
 			return
 		}
 
 		user, err := newUser.ToUser()
 		if err != nil {
-			http.Error(w, fmt.Sprintf("error validating new user: %v", err), http.StatusBadRequest)
+			respondError(w, newHTTPError(http.StatusBadRequest, "error validating new user: %v", err))
 			return
 		}
 		if err := c.UserStore.Insert(user); err != nil {
-			http.Error(w, fmt.Sprintf("error inserting new user into database: %v", err), http.StatusInternalServerError)
+			if errors.Is(err, users.ErrUserNameTaken) {
+				respondError(w, newHTTPError(http.StatusConflict, "sorry, but the user name '%s' is already taken", newUser.UserName))
+				return
+			}
+			respondError(w, err)
 			return
 		}
-		if _, err := c.SessionManager.BeginSession(w, NewSessionState(r, user)); err != nil {
-			http.Error(w, fmt.Sprintf("error begining new session: %v", err), http.StatusInternalServerError)
+		if err := c.startSession(w, r, user, false); err != nil {
+			respondError(w, err)
 			return
 		}
+		if len(user.Email) > 0 {
+			if err := c.sendEmailVerification(user); err != nil {
+				log.Printf("error sending verification email to new user '%s': %v", user.UserName, err)
+			}
+		}
 		w.Header().Add(headerLocation, "/users/"+url.PathEscape(user.UserName))
-		respond(w, user, http.StatusCreated)
+		respond(w, user.Private(), http.StatusCreated)
 
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		respondError(w, errMethodNotAllowed)
 		return
 	}
 }
@@ -57,7 +62,8 @@ func (c *Config) SpecificUserHandler(w http.ResponseWriter, r *http.Request, ses
 	if userName == "me" {
 		//optimization: if GET /users/me, respond with currently authenticated user
 		if r.Method == http.MethodGet {
-			respond(w, sessionState.User, http.StatusOK)
+			w.Header().Set(headerETag, etag(sessionState.User))
+			respond(w, sessionState.User.Private(), http.StatusOK)
 			return
 		}
 		userName = sessionState.User.UserName
@@ -65,47 +71,104 @@ func (c *Config) SpecificUserHandler(w http.ResponseWriter, r *http.Request, ses
 
 	switch r.Method {
 	case http.MethodGet:
-		//can read any user profile
+		//can read any user profile, but only the private
+		//fields of those the user has permission to view
 		user, err := c.UserStore.Get(userName)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("error getting user from database: %v", err), http.StatusInternalServerError)
+			respondError(w, err)
+			return
+		}
+		if sessionState.User.Can(users.PermViewPrivate, user) {
+			w.Header().Set(headerETag, etag(user))
+			respond(w, user.Private(), http.StatusOK)
+			return
+		}
+		//deleted accounts are hidden from other users during the grace period
+		if user.DeletionScheduled() {
+			respondError(w, users.ErrNotFound)
 			return
 		}
+		w.Header().Set(headerETag, etag(user))
 		respond(w, user, http.StatusOK)
 
 	case http.MethodPatch:
-		//may update only your own profile
-		if userName != sessionState.User.UserName {
-			http.Error(w, "you may not update profiles of other users", http.StatusForbidden)
+		target, err := c.authorizedTarget(sessionState, users.PermEditProfile, userName)
+		if err != nil {
+			respondError(w, err)
 			return
 		}
-		updates := &users.Updates{}
-		if err := receive(r, updates); err != nil {
-			http.Error(w, fmt.Sprintf("error receiving posted updates: %v", err), http.StatusBadRequest)
+		c.updateUser(w, r, sessionState, target)
+
+	case http.MethodDelete:
+		target, err := c.authorizedTarget(sessionState, users.PermDeleteAccount, userName)
+		if err != nil {
+			respondError(w, err)
 			return
 		}
-		user, err := c.UserStore.Update(userName, updates)
+		version, err := ifMatchVersion(r)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("error updating user profile: %v", err), http.StatusInternalServerError)
+			respondError(w, err)
 			return
 		}
-		respond(w, user, http.StatusOK)
-
-	case http.MethodDelete:
-		//may delete only your own profile
-		if userName != sessionState.User.UserName {
-			http.Error(w, "you may not delete profiles of other users", http.StatusForbidden)
+		if version != users.AnyVersion && version != target.Version {
+			respondError(w, users.ErrVersionMismatch)
 			return
 		}
-		if err := c.UserStore.Delete(userName); err != nil {
-			http.Error(w, fmt.Sprintf("error deleting user profile: %v", err), http.StatusInternalServerError)
+		//the account is only marked for deletion, and is purged
+		//by RunPurger once the grace period has passed
+		target.ScheduleDeletion(c.DeletionGracePeriod)
+		if err := c.UserStore.Save(target); err != nil {
+			respondError(w, err)
 			return
 		}
-		c.SessionManager.EndSession(r)
-		w.Write([]byte("account deleted"))
+		//end every session of the deleted user, not just this one
+		if target.UserName == sessionState.User.UserName {
+			c.SessionManager.EndSession(r)
+		}
+		if err := c.endAllSessions(target.UserName); err != nil {
+			log.Printf("error ending sessions of deleted user '%s': %v", target.UserName, err)
+		}
+		respond(w, target.Private(), http.StatusAccepted)
 
 	default:
-		http.Error(w, "method not allowed", http.StatusBadRequest)
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//updateUser applies the posted updates to the profile of target
+func (c *Config) updateUser(w http.ResponseWriter, r *http.Request, sessionState *SessionState, target *users.User) {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	updates := &users.Updates{}
+	if err := receive(r, updates); err != nil {
+		respondError(w, newHTTPError(http.StatusBadRequest, "error receiving posted updates: %v", err))
 		return
 	}
+	//email addresses and mobile numbers are used to recover accounts,
+	//so only the account's owner may change them and verify the new ones
+	if target.UserName != sessionState.User.UserName && (updates.Email != nil || updates.Mobile != nil) {
+		respondError(w, newHTTPError(http.StatusForbidden, "only the account's owner may change its email or mobile"))
+		return
+	}
+	//the directory updates these at each sign-in
+	if target.Directory && (updates.PersonalName != nil || updates.FamilyName != nil || updates.Email != nil) {
+		respondError(w, newHTTPError(http.StatusForbidden, "this account's name and email are managed by the directory"))
+		return
+	}
+	user, err := c.UserStore.Update(target.UserName, updates, version)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if updates.Email != nil && len(user.Email) > 0 {
+		if err := c.sendEmailVerification(user); err != nil {
+			log.Printf("error sending verification email to user '%s': %v", user.UserName, err)
+		}
+	}
+	w.Header().Set(headerETag, etag(user))
+	respond(w, user.Private(), http.StatusOK)
 }
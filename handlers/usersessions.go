@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/davestearns/sessions"
+	"github.com/davestearns/userservice/models/users"
+	"github.com/davestearns/userservice/models/usersessions"
+)
+
+//sessionView is the view of one of the user's sessions
+type sessionView struct {
+	ID           string    `json:"id"`
+	Began        time.Time `json:"began"`
+	ClientIPPath string    `json:"clientIPPath"`
+	UserAgent    string    `json:"userAgent"`
+	Current      bool      `json:"current"`
+}
+
+//startSession begins a new session for user and adds it to the user's session index
+func (c *Config) startSession(w http.ResponseWriter, r *http.Request, user *users.User, remember bool) error {
+	state := NewSessionState(r, user, remember)
+	sid, err := c.SessionManager.BeginSession(w, state)
+	if err != nil {
+		return err
+	}
+	return c.SessionIndex.Add(user.UserName,
+		usersessions.NewSession(string(sid), state.Began, state.ClientIPPath, r.UserAgent()))
+}
+
+//activeSessions returns the sessions in the user's index that are still
+//in the session store, unexpired, and began after the user's credentials
+//last changed, ending those that are no longer valid
+func (c *Config) activeSessions(user *users.User) ([]*usersessions.Session, error) {
+	indexed, err := c.SessionIndex.List(user.UserName)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var active []*usersessions.Session
+	for _, s := range indexed {
+		state := &SessionState{}
+		if err := c.SessionStore.Get(sessionID(s), state); err != nil ||
+			c.sessionLifetime(state).expired(state, now) || user.CredentialsChanged.After(state.Began) {
+			c.endIndexedSession(user.UserName, s)
+			continue
+		}
+		active = append(active, s)
+	}
+	return active, nil
+}
+
+//endCurrentSession ends the request's session and removes it from the user's index
+func (c *Config) endCurrentSession(r *http.Request, sessionState *SessionState) error {
+	if err := c.SessionManager.EndSession(r); err != nil {
+		return err
+	}
+	err := c.SessionIndex.Remove(sessionState.UserName, usersessions.PublicID(string(sessionState.ID)))
+	if err != nil && err != usersessions.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+//endIndexedSession deletes the session from the session store and the user's index
+func (c *Config) endIndexedSession(userName string, s *usersessions.Session) error {
+	if err := c.SessionStore.Delete(sessionID(s)); err != nil {
+		return err
+	}
+	if err := c.SessionIndex.Remove(userName, s.ID); err != nil && err != usersessions.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+//endAllSessions ends every indexed session of the user
+func (c *Config) endAllSessions(userName string) error {
+	indexed, err := c.SessionIndex.List(userName)
+	if err != nil {
+		return err
+	}
+	for _, s := range indexed {
+		if err := c.SessionStore.Delete(sessionID(s)); err != nil {
+			return err
+		}
+	}
+	return c.SessionIndex.RemoveAll(userName)
+}
+
+//listSessions responds with the current user's active sessions
+func (c *Config) listSessions(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	active, err := c.activeSessions(sessionState.User)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	currentID := usersessions.PublicID(string(sessionState.ID))
+	views := []*sessionView{}
+	for _, s := range active {
+		views = append(views, &sessionView{
+			ID:           s.ID,
+			Began:        s.Began,
+			ClientIPPath: s.ClientIPPath,
+			UserAgent:    s.UserAgent,
+			Current:      s.ID == currentID,
+		})
+	}
+	respond(w, views, http.StatusOK)
+}
+
+//endOtherSessions ends all of the current user's sessions except the current one
+func (c *Config) endOtherSessions(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	if sessionState.fromToken() {
+		//without a current session, every session would be "other"
+		respondError(w, newHTTPError(http.StatusBadRequest, "ending other sessions requires a session, not an access token"))
+		return
+	}
+	userName := sessionState.User.UserName
+	indexed, err := c.SessionIndex.List(userName)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	currentID := usersessions.PublicID(string(sessionState.ID))
+	ended := 0
+	for _, s := range indexed {
+		if s.ID == currentID {
+			continue
+		}
+		if err := c.endIndexedSession(userName, s); err != nil {
+			respondError(w, err)
+			return
+		}
+		ended++
+	}
+	w.Write([]byte(fmt.Sprintf("ended %d other sessions", ended)))
+}
+
+//SpecificSessionHandler handles requests for the /sessions/<id> resource
+func (c *Config) SpecificSessionHandler(w http.ResponseWriter, r *http.Request, sessionState *SessionState) {
+	switch r.Method {
+	case http.MethodDelete:
+		userName := sessionState.User.UserName
+		s, err := c.SessionIndex.Get(userName, path.Base(r.URL.Path))
+		if err != nil {
+			if err == usersessions.ErrNotFound {
+				respondError(w, newHTTPError(http.StatusNotFound, err.Error()))
+				return
+			}
+			respondError(w, err)
+			return
+		}
+		if err := c.endIndexedSession(userName, s); err != nil {
+			respondError(w, err)
+			return
+		}
+		w.Write([]byte("session ended"))
+
+	default:
+		respondError(w, errMethodNotAllowed)
+		return
+	}
+}
+
+//sessionID returns the session ID of an indexed session
+func sessionID(s *usersessions.Session) sessions.SessionID {
+	return sessions.SessionID(s.SessionID)
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+)
+
+//Algorithm names used in the JWT "alg" header
+const (
+	EdDSA = "EdDSA"
+	RS256 = "RS256"
+)
+
+//Key is a private key used to sign and verify JWTs
+type Key struct {
+	//ID identifies the key in the JWT "kid" header,
+	//and is derived from the public key
+	ID string
+	//Algorithm is the signing algorithm used with the key
+	Algorithm string
+	private   crypto.Signer
+}
+
+//NewEdDSAKey derives an Ed25519 key from secret, so that JWT signing
+//keys can be rotated along with the secrets that derive them
+func NewEdDSAKey(secret string) (*Key, error) {
+	seed := sha256.Sum256([]byte("jwt-eddsa\x00" + secret))
+	return newKey(EdDSA, ed25519.NewKeyFromSeed(seed[:]))
+}
+
+//ParseRSAKey parses a PEM-encoded PKCS #1 or PKCS #8 RSA private key
+func ParseRSAKey(pemBytes []byte) (*Key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if pk, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newKey(RS256, pk)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+	pk, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return newKey(RS256, pk)
+}
+
+//newKey constructs a new Key, deriving its ID from the public key
+func newKey(algorithm string, private crypto.Signer) (*Key, error) {
+	der, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding public key: %v", err)
+	}
+	h := sha256.Sum256(der)
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(h[:12]),
+		Algorithm: algorithm,
+		private:   private,
+	}, nil
+}
+
+//Public returns the public key
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+//sign returns the signature of input
+func (k *Key) sign(input []byte) ([]byte, error) {
+	if k.Algorithm == RS256 {
+		h := sha256.Sum256(input)
+		return k.private.Sign(nil, h[:], crypto.SHA256)
+	}
+	return k.private.Sign(nil, input, crypto.Hash(0))
+}
+
+//verify returns true if sig is a valid signature of input
+func (k *Key) verify(input []byte, sig []byte) bool {
+	switch pub := k.Public().(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, input, sig)
+	case *rsa.PublicKey:
+		h := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,136 @@
+//Package jwt signs and verifies JSON Web Tokens using
+//asymmetric keys, so that other services can verify them
+//using only the public keys
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//ErrInvalidToken is returned from Verify when the token is malformed
+//or its signature doesn't match any of the keys
+var ErrInvalidToken = errors.New("invalid token")
+
+//ErrExpiredToken is returned from Verify when the token has expired
+var ErrExpiredToken = errors.New("token has expired")
+
+//Claims are the registered JWT claims, which should be
+//embedded in application-specific claims structs
+type Claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	Expires   int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+//header is the JOSE header of a token
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+//Signer signs and verifies JWTs
+type Signer struct {
+	keys []*Key
+}
+
+//NewSigner constructs a new Signer. The first key is used to sign
+//new tokens; all keys are used to verify tokens, so that keys can be
+//rotated without invalidating existing tokens.
+func NewSigner(keys []*Key) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one signing key must be supplied")
+	}
+	return &Signer{keys: keys}, nil
+}
+
+//Keys returns the keys used by the Signer
+func (s *Signer) Keys() []*Key {
+	return s.keys
+}
+
+//Sign returns a new JWT containing claims
+func (s *Signer) Sign(claims interface{}) (string, error) {
+	key := s.keys[0]
+	hj, err := json.Marshal(&header{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("error encoding header: %v", err)
+	}
+	cj, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("error encoding claims: %v", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(hj) + "." + base64.RawURLEncoding.EncodeToString(cj)
+	sig, err := key.sign([]byte(input))
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+//Verify verifies the token and decodes its claims into the claims parameter.
+//Tokens whose "exp" claim has passed, or whose "nbf" claim has not yet
+//arrived, are rejected.
+func (s *Signer) Verify(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+	hj, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	h := &header{}
+	if err := json.Unmarshal(hj, h); err != nil {
+		return ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	key := s.key(h.KeyID)
+	//the algorithm must match the key, so that a token can't
+	//select a weaker algorithm than the one the key was issued for
+	if key == nil || key.Algorithm != h.Algorithm || !key.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return ErrInvalidToken
+	}
+
+	cj, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	registered := &Claims{}
+	if err := json.Unmarshal(cj, registered); err != nil {
+		return ErrInvalidToken
+	}
+	now := time.Now().Unix()
+	if registered.Expires != 0 && now > registered.Expires {
+		return ErrExpiredToken
+	}
+	if registered.NotBefore != 0 && now < registered.NotBefore {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(cj, claims); err != nil {
+		return fmt.Errorf("error decoding claims: %v", err)
+	}
+	return nil
+}
+
+//key returns the key with the given ID, or nil if there is none
+func (s *Signer) key(id string) *Key {
+	for _, k := range s.keys {
+		if k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	Claims
+	Name string `json:"name"`
+}
+
+func newTestRSAKey(t *testing.T) *Key {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
+	key, err := ParseRSAKey(pemBytes)
+	if err != nil {
+		t.Fatalf("error parsing RSA key: %v", err)
+	}
+	return key
+}
+
+func TestSigner(t *testing.T) {
+	oldKey, err := NewEdDSAKey("old-secret")
+	if err != nil {
+		t.Fatalf("error deriving key: %v", err)
+	}
+	newKey, _ := NewEdDSAKey("new-secret")
+	again, _ := NewEdDSAKey("new-secret")
+	if again.ID != newKey.ID {
+		t.Errorf("keys derived from the same secret must have the same ID")
+	}
+
+	cases := []struct {
+		name string
+		keys []*Key
+	}{
+		{"EdDSA", []*Key{newKey, oldKey}},
+		{"RS256", []*Key{newTestRSAKey(t), oldKey}},
+	}
+	for _, c := range cases {
+		signer, err := NewSigner(c.keys)
+		if err != nil {
+			t.Fatalf("case %s: error constructing signer: %v", c.name, err)
+		}
+		exp := time.Now().Add(time.Hour).Unix()
+		token, err := signer.Sign(&testClaims{Claims: Claims{Subject: "tester", Expires: exp}, Name: "Test"})
+		if err != nil {
+			t.Fatalf("case %s: error signing token: %v", c.name, err)
+		}
+		claims := &testClaims{}
+		if err := signer.Verify(token, claims); err != nil {
+			t.Fatalf("case %s: error verifying token: %v", c.name, err)
+		}
+		if claims.Subject != "tester" || claims.Name != "Test" {
+			t.Errorf("case %s: incorrect claims: %+v", c.name, claims)
+		}
+
+		//tokens signed with an older key must still verify
+		oldSigner, _ := NewSigner([]*Key{oldKey})
+		oldToken, _ := oldSigner.Sign(&testClaims{Claims: Claims{Subject: "tester"}})
+		if err := signer.Verify(oldToken, &testClaims{}); err != nil {
+			t.Errorf("case %s: error verifying token signed with older key: %v", c.name, err)
+		}
+
+		parts := strings.Split(token, ".")
+		invalid := []string{
+			"not-a-token",
+			parts[0] + "." + parts[1] + ".x" + parts[2],
+			parts[0] + "." + oldToken[strings.Index(oldToken, ".")+1:],
+		}
+		for _, tok := range invalid {
+			if err := signer.Verify(tok, &testClaims{}); err != ErrInvalidToken {
+				t.Errorf("case %s: expected %v but got %v", c.name, ErrInvalidToken, err)
+			}
+		}
+
+		expired, _ := signer.Sign(&testClaims{Claims: Claims{Expires: time.Now().Add(-time.Minute).Unix()}})
+		if err := signer.Verify(expired, &testClaims{}); err != ErrExpiredToken {
+			t.Errorf("case %s: incorrect error verifying expired token: expected %v but got %v", c.name, ErrExpiredToken, err)
+		}
+	}
+
+	//a token from a signer that doesn't have the key must not verify
+	otherSigner, _ := NewSigner([]*Key{newKey})
+	oldSigner, _ := NewSigner([]*Key{oldKey})
+	token, _ := otherSigner.Sign(&testClaims{})
+	if err := oldSigner.Verify(token, &testClaims{}); err != ErrInvalidToken {
+		t.Errorf("token verified with unknown key: expected %v but got %v", ErrInvalidToken, err)
+	}
+}
+
+func TestJWKSet(t *testing.T) {
+	edKey, _ := NewEdDSAKey("secret")
+	rsaKey := newTestRSAKey(t)
+	signer, _ := NewSigner([]*Key{edKey, rsaKey})
+
+	set := signer.JWKSet()
+	if len(set.Keys) != 2 {
+		t.Fatalf("incorrect number of keys: expected 2 but got %d", len(set.Keys))
+	}
+	ed := set.Keys[0]
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.KeyID != edKey.ID || ed.Algorithm != EdDSA || len(ed.X) == 0 {
+		t.Errorf("incorrect Ed25519 JWK: %+v", ed)
+	}
+	rs := set.Keys[1]
+	if rs.KeyType != "RSA" || rs.KeyID != rsaKey.ID || rs.Algorithm != RS256 || len(rs.N) == 0 || rs.E != "AQAB" {
+		t.Errorf("incorrect RSA JWK: %+v", rs)
+	}
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+//LogMailer is an implementation of the Mailer interface that writes
+//messages to an io.Writer (e.g., a file or os.Stdout) instead of sending
+//them. It also retains sent messages so that automated tests can inspect them.
+type LogMailer struct {
+	mx   sync.Mutex
+	w    io.Writer
+	sent []*Message
+}
+
+//NewLogMailer constructs a new LogMailer that writes messages to w.
+//If w is nil, messages are only retained.
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{
+		w: w,
+	}
+}
+
+//Send writes the message
+func (lm *LogMailer) Send(msg *Message) error {
+	lm.mx.Lock()
+	defer lm.mx.Unlock()
+	if lm.w != nil {
+		if _, err := fmt.Fprintf(lm.w, "To: %s\nSubject: %s\n\n%s\n\n", msg.To, msg.Subject, msg.Body); err != nil {
+			return fmt.Errorf("error writing email: %v", err)
+		}
+	}
+	sent := *msg
+	lm.sent = append(lm.sent, &sent)
+	return nil
+}
+
+//Sent returns all messages sent so far
+func (lm *LogMailer) Sent() []*Message {
+	lm.mx.Lock()
+	defer lm.mx.Unlock()
+	return append([]*Message(nil), lm.sent...)
+}
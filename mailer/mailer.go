@@ -0,0 +1,14 @@
+//Package mailer sends email messages to users
+package mailer
+
+//Message is an email message
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//Mailer describes what a mailer can do
+type Mailer interface {
+	Send(msg *Message) error
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+//SMTPMailer is an implementation of the Mailer interface
+//that sends messages through an SMTP server
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+//NewSMTPMailer constructs a new SMTPMailer that sends messages through the
+//SMTP server at addr (host:port) from the address in from. If userName is
+//non-zero-length, the mailer authenticates using PLAIN auth.
+func NewSMTPMailer(addr string, from string, userName string, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP server address '%s': %v", addr, err)
+	}
+	var auth smtp.Auth
+	if len(userName) > 0 {
+		auth = smtp.PlainAuth("", userName, password, host)
+	}
+	return &SMTPMailer{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}, nil
+}
+
+//Send sends the message
+func (sm *SMTPMailer) Send(msg *Message) error {
+	//reject header injection via the recipient or subject
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid message headers")
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", sm.from)
+	fmt.Fprintf(buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(msg.Body)
+
+	if err := smtp.SendMail(sm.addr, sm.auth, sm.from, []string{msg.To}, buf.Bytes()); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+	return nil
+}
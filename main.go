@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -14,16 +19,140 @@ import (
 
 	"github.com/caarlos0/env"
 	"github.com/davestearns/sessions"
+	"github.com/davestearns/userservice/authn"
+	"github.com/davestearns/userservice/breached"
+	"github.com/davestearns/userservice/federation"
 	"github.com/davestearns/userservice/handlers"
+	"github.com/davestearns/userservice/jwt"
+	"github.com/davestearns/userservice/mailer"
+	"github.com/davestearns/userservice/models/authcodes"
+	"github.com/davestearns/userservice/models/challenges"
+	"github.com/davestearns/userservice/models/clients"
+	"github.com/davestearns/userservice/models/identities"
+	"github.com/davestearns/userservice/models/ratelimit"
+	"github.com/davestearns/userservice/models/refreshtokens"
+	"github.com/davestearns/userservice/models/resets"
+	"github.com/davestearns/userservice/models/smscodes"
+	"github.com/davestearns/userservice/models/throttle"
 	"github.com/davestearns/userservice/models/users"
+	"github.com/davestearns/userservice/models/usersessions"
+	"github.com/davestearns/userservice/passhash"
+	"github.com/davestearns/userservice/sealing"
+	"github.com/davestearns/userservice/signing"
+	"github.com/davestearns/userservice/sms"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gomodule/redigo/redis"
+	_ "github.com/lib/pq"
 )
 
 type config struct {
-	Addr          string   `env:"ADDR" envDefault:":80"`
-	RedisAddr     string   `env:"REDIS_ADDR" envDefault:"cache.info441.info:6379"`
-	SessionKeys   []string `env:"SESSION_KEYS"`
-	DynamoDBTable string   `env:"DYNAMODB_TABLE" envDefault:"users"`
-	DynamoDBKey   string   `env:"DYNAMODB_KEY" envDefault:"userName"`
+	Addr                   string        `env:"ADDR" envDefault:":80"`
+	RedisAddr              string        `env:"REDIS_ADDR" envDefault:"cache.info441.info:6379"`
+	SessionKeys            []string      `env:"SESSION_KEYS"`
+	SessionIdleTimeout     time.Duration `env:"SESSION_IDLE_TIMEOUT" envDefault:"1h"`
+	SessionMaxAge          time.Duration `env:"SESSION_MAX_AGE" envDefault:"24h"`
+	RememberIdleTimeout    time.Duration `env:"REMEMBER_IDLE_TIMEOUT" envDefault:"336h"`
+	RememberMaxAge         time.Duration `env:"REMEMBER_MAX_AGE" envDefault:"720h"`
+	UserStore              string        `env:"USER_STORE" envDefault:"dynamodb"`
+	DynamoDBTable          string        `env:"DYNAMODB_TABLE" envDefault:"users"`
+	DynamoDBKey            string        `env:"DYNAMODB_KEY" envDefault:"userName"`
+	PostgresDSN            string        `env:"POSTGRES_DSN"`
+	BoltPath               string        `env:"BOLT_PATH" envDefault:"users.db"`
+	TokenStore             string        `env:"TOKEN_STORE" envDefault:"redis"`
+	Mailer                 string        `env:"MAILER" envDefault:"log"`
+	SMTPAddr               string        `env:"SMTP_ADDR"`
+	SMTPFrom               string        `env:"SMTP_FROM"`
+	SMTPUser               string        `env:"SMTP_USER"`
+	SMTPPassword           string        `env:"SMTP_PASSWORD"`
+	ResetURL               string        `env:"PASSWORD_RESET_URL" envDefault:"http://localhost/password-resets/"`
+	ResetTTL               time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	VerifyURL              string        `env:"VERIFY_EMAIL_URL" envDefault:"http://localhost/email-verifications/"`
+	VerifyTTL              time.Duration `env:"VERIFY_EMAIL_TTL" envDefault:"72h"`
+	CallingCode            string        `env:"DEFAULT_CALLING_CODE" envDefault:"1"`
+	SMSSender              string        `env:"SMS_SENDER" envDefault:"fake"`
+	TwilioSID              string        `env:"TWILIO_ACCOUNT_SID"`
+	TwilioToken            string        `env:"TWILIO_AUTH_TOKEN"`
+	TwilioFrom             string        `env:"TWILIO_FROM"`
+	SMSCodeTTL             time.Duration `env:"SMS_CODE_TTL" envDefault:"10m"`
+	MFAKeys                []string      `env:"MFA_KEYS"`
+	MFAIssuer              string        `env:"MFA_ISSUER" envDefault:"userservice"`
+	MFAChallengeTTL        time.Duration `env:"MFA_CHALLENGE_TTL" envDefault:"5m"`
+	WebAuthnRPID           string        `env:"WEBAUTHN_RP_ID" envDefault:"localhost"`
+	WebAuthnRPName         string        `env:"WEBAUTHN_RP_NAME" envDefault:"userservice"`
+	WebAuthnOrigins        []string      `env:"WEBAUTHN_ORIGINS" envDefault:"http://localhost"`
+	PasskeyChallengeTTL    time.Duration `env:"PASSKEY_CHALLENGE_TTL" envDefault:"5m"`
+	SignInFreeAttemptsUser int           `env:"SIGNIN_FREE_ATTEMPTS_USER" envDefault:"5"`
+	SignInFreeAttemptsIP   int           `env:"SIGNIN_FREE_ATTEMPTS_IP" envDefault:"20"`
+	SignInBaseDelay        time.Duration `env:"SIGNIN_BASE_DELAY" envDefault:"1s"`
+	SignInMaxDelay         time.Duration `env:"SIGNIN_MAX_DELAY" envDefault:"15m"`
+	SignInFailureWindow    time.Duration `env:"SIGNIN_FAILURE_WINDOW" envDefault:"1h"`
+	PasswordHasher         string        `env:"PASSWORD_HASHER" envDefault:"bcrypt"`
+	BcryptCost             int           `env:"BCRYPT_COST" envDefault:"10"`
+	Argon2Memory           uint32        `env:"ARGON2_MEMORY" envDefault:"65536"`
+	Argon2Time             uint32        `env:"ARGON2_TIME" envDefault:"3"`
+	Argon2Threads          uint8         `env:"ARGON2_THREADS" envDefault:"2"`
+	PasswordMinLength      int           `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	PasswordMinScore       int           `env:"PASSWORD_MIN_SCORE" envDefault:"2"`
+	PasswordBreachCheck    string        `env:"PASSWORD_BREACH_CHECK" envDefault:"off"`
+	PasswordBreachFile     string        `env:"PASSWORD_BREACH_FILE"`
+	PasswordBreachURL      string        `env:"PASSWORD_BREACH_URL" envDefault:"https://api.pwnedpasswords.com/range/"`
+	RateLimits             []string      `env:"RATE_LIMITS" envDefault:"POST /users 10/1h,PATCH /users/ 60/1m,POST /sessions 30/1m,POST /tokens 30/1m,* / 600/1m"`
+	TokenIssuer            string        `env:"TOKEN_ISSUER" envDefault:"http://localhost"`
+	AccessTokenTTL         time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL        time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	JWTAlgorithm           string        `env:"JWT_ALGORITHM" envDefault:"EdDSA"`
+	JWTRSAKeyFiles         []string      `env:"JWT_RSA_KEY_FILES"`
+	AuthCodeTTL            time.Duration `env:"AUTH_CODE_TTL" envDefault:"1m"`
+	SignInURL              string        `env:"SIGNIN_URL" envDefault:"http://localhost/signin"`
+	OIDCProvidersFile      string        `env:"OIDC_PROVIDERS_FILE"`
+	ExternalSignInTTL      time.Duration `env:"EXTERNAL_SIGNIN_TTL" envDefault:"10m"`
+	Authenticator          string        `env:"AUTHENTICATOR" envDefault:"local"`
+	LDAPURL                string        `env:"LDAP_URL"`
+	LDAPStartTLS           bool          `env:"LDAP_START_TLS"`
+	LDAPBindDN             string        `env:"LDAP_BIND_DN"`
+	LDAPBindPassword       string        `env:"LDAP_BIND_PASSWORD"`
+	LDAPBaseDN             string        `env:"LDAP_BASE_DN"`
+	LDAPFilter             string        `env:"LDAP_FILTER" envDefault:"(&(objectClass=person)(uid=%s))"`
+	LDAPUserNameAttr       string        `env:"LDAP_USERNAME_ATTR" envDefault:"uid"`
+	LDAPPersonalNameAttr   string        `env:"LDAP_PERSONAL_NAME_ATTR" envDefault:"givenName"`
+	LDAPFamilyNameAttr     string        `env:"LDAP_FAMILY_NAME_ATTR" envDefault:"sn"`
+	LDAPEmailAttr          string        `env:"LDAP_EMAIL_ATTR" envDefault:"mail"`
+	AdminUserNames         []string      `env:"ADMIN_USER_NAMES"`
+	DeletionGracePeriod    time.Duration `env:"DELETION_GRACE_PERIOD" envDefault:"720h"`
+	PurgeInterval          time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+	SMSSendLimit           int           `env:"SMS_SEND_LIMIT" envDefault:"5"`
+	SMSSendPeriod          time.Duration `env:"SMS_SEND_PERIOD" envDefault:"1h"`
+	RefreshFamilyMaxAge    time.Duration `env:"REFRESH_FAMILY_MAX_AGE" envDefault:"2160h"`
+	DynamoDBClientTable    string        `env:"DYNAMODB_CLIENT_TABLE" envDefault:"clients"`
+	DynamoDBLinkTable      string        `env:"DYNAMODB_LINK_TABLE" envDefault:"identity_links"`
+}
+
+//redactedValue replaces secrets in the logged configuration
+const redactedValue = "[redacted]"
+
+//redacted returns a copy of the configuration that is safe to log,
+//with its keys, passwords and tokens replaced by redactedValue
+func (cfg config) redacted() config {
+	redactList := func(values []string) []string {
+		if len(values) == 0 {
+			return values
+		}
+		return []string{redactedValue}
+	}
+	redactString := func(value string) string {
+		if len(value) == 0 {
+			return value
+		}
+		return redactedValue
+	}
+	cfg.SessionKeys = redactList(cfg.SessionKeys)
+	cfg.MFAKeys = redactList(cfg.MFAKeys)
+	//the DSN may include a password
+	cfg.PostgresDSN = redactString(cfg.PostgresDSN)
+	cfg.SMTPPassword = redactString(cfg.SMTPPassword)
+	cfg.TwilioToken = redactString(cfg.TwilioToken)
+	cfg.LDAPBindPassword = redactString(cfg.LDAPBindPassword)
+	return cfg
 }
 
 func fetchSigningKeys(awsSession *session.Session) ([]string, error) {
@@ -40,12 +169,346 @@ func fetchSigningKeys(awsSession *session.Session) ([]string, error) {
 	return strings.Split(*result.SecretString, ","), nil
 }
 
+//newUserStore constructs the users.Store implementation selected by cfg.UserStore
+func newUserStore(cfg *config, awsSession *session.Session) (users.Store, error) {
+	switch cfg.UserStore {
+	case "dynamodb":
+		return users.NewDynamoDBStore(dynamodb.New(awsSession), cfg.DynamoDBTable, cfg.DynamoDBKey), nil
+	case "postgres":
+		db, err := sql.Open("postgres", cfg.PostgresDSN)
+		if err != nil {
+			return nil, fmt.Errorf("error opening PostgreSQL database: %v", err)
+		}
+		return users.NewPostgresStore(db)
+	case "bolt":
+		return users.NewBoltStore(cfg.BoltPath)
+	case "memory":
+		log.Printf("WARNING: using in-memory user store; all accounts will be lost when the server exits")
+		return users.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown user store '%s'", cfg.UserStore)
+	}
+}
+
+//newResetStore constructs the resets.Store implementation selected by cfg.TokenStore
+func newResetStore(cfg *config, redisPool *redis.Pool) (resets.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return resets.NewRedisStore(redisPool), nil
+	case "memory":
+		return resets.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newSMSCodeStore constructs the smscodes.Store implementation selected by cfg.TokenStore
+func newSMSCodeStore(cfg *config, redisPool *redis.Pool) (smscodes.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return smscodes.NewRedisStore(redisPool), nil
+	case "memory":
+		return smscodes.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newChallengeStore constructs the challenges.Store implementation selected by cfg.TokenStore
+func newChallengeStore(cfg *config, redisPool *redis.Pool) (challenges.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return challenges.NewRedisStore(redisPool), nil
+	case "memory":
+		return challenges.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newThrottleStore constructs the throttle.Store implementation selected by cfg.TokenStore
+func newThrottleStore(cfg *config, redisPool *redis.Pool) (throttle.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return throttle.NewRedisStore(redisPool), nil
+	case "memory":
+		return throttle.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newRateLimitStore constructs the ratelimit.Store implementation selected by cfg.TokenStore
+func newRateLimitStore(cfg *config, redisPool *redis.Pool) (ratelimit.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return ratelimit.NewRedisStore(redisPool), nil
+	case "memory":
+		return ratelimit.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newSessionIndex constructs the usersessions.Store implementation selected by cfg.TokenStore
+func newSessionIndex(cfg *config, redisPool *redis.Pool, ttl time.Duration) (usersessions.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return usersessions.NewRedisStore(redisPool, ttl), nil
+	case "memory":
+		return usersessions.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newPasswordHasher constructs the passhash.Hasher implementation selected by cfg.PasswordHasher
+func newPasswordHasher(cfg *config) (passhash.Hasher, error) {
+	switch cfg.PasswordHasher {
+	case "bcrypt":
+		return &passhash.Bcrypt{Cost: cfg.BcryptCost}, nil
+	case "argon2id":
+		return &passhash.Argon2id{
+			Memory:    cfg.Argon2Memory,
+			Time:      cfg.Argon2Time,
+			Threads:   cfg.Argon2Threads,
+			KeyLength: 32,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher '%s'", cfg.PasswordHasher)
+	}
+}
+
+//newAuthenticator constructs the authn.Authenticator implementation selected by cfg.Authenticator
+func newAuthenticator(cfg *config, userStore users.Store) (authn.Authenticator, error) {
+	switch cfg.Authenticator {
+	case "local":
+		return authn.NewLocal(userStore), nil
+	case "ldap":
+		return authn.NewLDAP(&authn.LDAPConfig{
+			URL:          cfg.LDAPURL,
+			StartTLS:     cfg.LDAPStartTLS,
+			BindDN:       cfg.LDAPBindDN,
+			BindPassword: cfg.LDAPBindPassword,
+			BaseDN:       cfg.LDAPBaseDN,
+			Filter:       cfg.LDAPFilter,
+			Attributes: authn.LDAPAttributes{
+				UserName:     cfg.LDAPUserNameAttr,
+				PersonalName: cfg.LDAPPersonalNameAttr,
+				FamilyName:   cfg.LDAPFamilyNameAttr,
+				Email:        cfg.LDAPEmailAttr,
+			},
+		}, userStore)
+	default:
+		return nil, fmt.Errorf("unknown authenticator '%s'", cfg.Authenticator)
+	}
+}
+
+//promoteAdmins assigns the admin role to the users named in
+//cfg.AdminUserNames, so that there is someone to assign other roles
+func promoteAdmins(cfg *config, userStore users.Store) error {
+	for _, userName := range cfg.AdminUserNames {
+		user, err := userStore.Get(userName)
+		if err != nil {
+			return fmt.Errorf("error getting admin user '%s': %v", userName, err)
+		}
+		if user.Role == users.RoleAdmin {
+			continue
+		}
+		user.Role = users.RoleAdmin
+		if err := userStore.Save(user); err != nil {
+			return fmt.Errorf("error promoting admin user '%s': %v", userName, err)
+		}
+		log.Printf("assigned admin role to %s", userName)
+	}
+	return nil
+}
+
+//newPasswordPolicy constructs the password policy configured by cfg
+func newPasswordPolicy(cfg *config) (*users.PasswordPolicy, error) {
+	policy := &users.PasswordPolicy{
+		MinLength: cfg.PasswordMinLength,
+		MinScore:  cfg.PasswordMinScore,
+	}
+	switch cfg.PasswordBreachCheck {
+	case "off":
+	case "file":
+		fileRange, err := breached.NewFileRange(cfg.PasswordBreachFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breaches = breached.NewChecker(fileRange)
+	case "api":
+		policy.Breaches = breached.NewChecker(breached.NewHTTPRange(cfg.PasswordBreachURL))
+	default:
+		return nil, fmt.Errorf("unknown password breach check '%s'", cfg.PasswordBreachCheck)
+	}
+	return policy, nil
+}
+
+//newAccessTokenSigner constructs a jwt.Signer using the algorithm selected by
+//cfg.JWTAlgorithm. EdDSA keys are derived from the session keys, so they are
+//rotated along with them; RS256 keys are read from PEM files, which should be
+//listed newest first and rotated along with the session keys.
+func newAccessTokenSigner(cfg *config) (*jwt.Signer, error) {
+	var keys []*jwt.Key
+	switch cfg.JWTAlgorithm {
+	case jwt.EdDSA:
+		for _, secret := range cfg.SessionKeys {
+			key, err := jwt.NewEdDSAKey(secret)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	case jwt.RS256:
+		for _, fileName := range cfg.JWTRSAKeyFiles {
+			pemBytes, err := ioutil.ReadFile(fileName)
+			if err != nil {
+				return nil, fmt.Errorf("error reading JWT key file: %v", err)
+			}
+			key, err := jwt.ParseRSAKey(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing JWT key file %s: %v", fileName, err)
+			}
+			keys = append(keys, key)
+		}
+	default:
+		return nil, fmt.Errorf("unknown JWT algorithm '%s'", cfg.JWTAlgorithm)
+	}
+	return jwt.NewSigner(keys)
+}
+
+//newRefreshTokenStore constructs the refreshtokens.Store implementation selected by cfg.TokenStore
+func newRefreshTokenStore(cfg *config, redisPool *redis.Pool) (refreshtokens.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return refreshtokens.NewRedisStore(redisPool), nil
+	case "memory":
+		return refreshtokens.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newClientStore constructs the clients.Store implementation selected by cfg.UserStore,
+//which shares the user store's database so that clients are just as durable
+func newClientStore(cfg *config, awsSession *session.Session, userStore users.Store) (clients.Store, error) {
+	switch cfg.UserStore {
+	case "dynamodb":
+		return clients.NewDynamoDBStore(dynamodb.New(awsSession), cfg.DynamoDBClientTable), nil
+	case "postgres":
+		return clients.NewPostgresStore(userStore.(*users.PostgresStore).DB()), nil
+	case "bolt":
+		return clients.NewBoltStore(userStore.(*users.BoltStore).DB())
+	case "memory":
+		return clients.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown user store '%s'", cfg.UserStore)
+	}
+}
+
+//newAuthCodeStore constructs the authcodes.Store implementation selected by cfg.TokenStore
+func newAuthCodeStore(cfg *config, redisPool *redis.Pool) (authcodes.Store, error) {
+	switch cfg.TokenStore {
+	case "redis":
+		return authcodes.NewRedisStore(redisPool), nil
+	case "memory":
+		return authcodes.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown token store '%s'", cfg.TokenStore)
+	}
+}
+
+//newIdentityStore constructs the identities.Store implementation selected by cfg.UserStore,
+//which shares the user store's database so that links are just as durable
+func newIdentityStore(cfg *config, awsSession *session.Session, userStore users.Store) (identities.Store, error) {
+	switch cfg.UserStore {
+	case "dynamodb":
+		return identities.NewDynamoDBStore(dynamodb.New(awsSession), cfg.DynamoDBLinkTable), nil
+	case "postgres":
+		return identities.NewPostgresStore(userStore.(*users.PostgresStore).DB()), nil
+	case "bolt":
+		return identities.NewBoltStore(userStore.(*users.BoltStore).DB())
+	case "memory":
+		return identities.NewMemStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown user store '%s'", cfg.UserStore)
+	}
+}
+
+//newExternalProviders constructs the external OpenID Connect providers
+//configured in cfg.OIDCProvidersFile, which contains a JSON array of
+//federation.ProviderConfig; there are none if no file is configured
+func newExternalProviders(cfg *config) (map[string]*federation.Provider, error) {
+	providers := map[string]*federation.Provider{}
+	if len(cfg.OIDCProvidersFile) == 0 {
+		return providers, nil
+	}
+	data, err := ioutil.ReadFile(cfg.OIDCProvidersFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading providers file: %v", err)
+	}
+	var configs []*federation.ProviderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("error parsing providers file: %v", err)
+	}
+	for _, providerConfig := range configs {
+		if _, found := providers[providerConfig.Name]; found {
+			return nil, fmt.Errorf("duplicate provider name '%s'", providerConfig.Name)
+		}
+		provider, err := federation.NewProvider(context.Background(), providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		providers[provider.Name] = provider
+	}
+	return providers, nil
+}
+
+//newSMSSender constructs the sms.Sender implementation selected by cfg.SMSSender
+func newSMSSender(cfg *config) (sms.Sender, error) {
+	switch cfg.SMSSender {
+	case "twilio":
+		return sms.NewTwilioSender(cfg.TwilioSID, cfg.TwilioToken, cfg.TwilioFrom), nil
+	case "fake":
+		log.Printf("WARNING: text messages will be written to stdout instead of being sent")
+		return sms.NewFakeSender(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS sender '%s'", cfg.SMSSender)
+	}
+}
+
+//newMailer constructs the mailer.Mailer implementation selected by cfg.Mailer
+func newMailer(cfg *config) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUser, cfg.SMTPPassword)
+	case "log":
+		log.Printf("WARNING: emails will be written to stdout instead of being sent")
+		return mailer.NewLogMailer(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer '%s'", cfg.Mailer)
+	}
+}
+
 func main() {
 	cfg := config{}
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatalf("error loading configuration: %v", err)
 	}
-	log.Printf("using the following configuration: %+v", cfg)
+	log.Printf("using the following configuration: %+v", cfg.redacted())
+	users.DefaultCallingCode = cfg.CallingCode
+	hasher, err := newPasswordHasher(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing password hasher: %v", err)
+	}
+	users.PasswordHasher = hasher
+	policy, err := newPasswordPolicy(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing password policy: %v", err)
+	}
+	users.CurrentPasswordPolicy = policy
 
 	//create a new AWS session
 	awsSession, err := session.NewSession()
@@ -64,25 +527,233 @@ func main() {
 		cfg.SessionKeys = keys
 	}
 
-	//construct a new DynamoDB client
-	dynamoClient := dynamodb.New(awsSession)
+	//construct the user store
+	userStore, err := newUserStore(&cfg, awsSession)
+	if err != nil {
+		log.Fatalf("error constructing user store: %v", err)
+	}
+	if err := promoteAdmins(&cfg, userStore); err != nil {
+		log.Fatalf("error promoting admins: %v", err)
+	}
+	authenticator, err := newAuthenticator(&cfg, userStore)
+	if err != nil {
+		log.Fatalf("error constructing authenticator: %v", err)
+	}
+
+	//construct a new redis session store; sessions are expired by
+	//EnsureSession, so entries need only last as long as the longest session
+	sessionTTL := cfg.SessionMaxAge
+	if cfg.RememberMaxAge > sessionTTL {
+		sessionTTL = cfg.RememberMaxAge
+	}
+	redisPool := sessions.NewRedisPool(cfg.RedisAddr, time.Minute*10)
+	sessionStore := sessions.NewRedisStore(redisPool, sessionTTL)
+	sessionIndex, err := newSessionIndex(&cfg, redisPool, sessionTTL)
+	if err != nil {
+		log.Fatalf("error constructing session index: %v", err)
+	}
+
+	resetStore, err := newResetStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing reset token store: %v", err)
+	}
+	emailer, err := newMailer(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing mailer: %v", err)
+	}
 
-	//construct a new redis session store
-	sessionStore := sessions.NewRedisStore(sessions.NewRedisPool(cfg.RedisAddr, time.Minute*10), time.Hour)
+	smsCodeStore, err := newSMSCodeStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing SMS code store: %v", err)
+	}
+	smsSender, err := newSMSSender(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing SMS sender: %v", err)
+	}
+	emailSigner, err := signing.NewSigner("email-verification", cfg.SessionKeys)
+	if err != nil {
+		log.Fatalf("error constructing email verification signer: %v", err)
+	}
+
+	//TOTP secrets are sealed with MFA_KEYS if supplied, else the session keys;
+	//retire old keys carefully, as secrets sealed with them can't be opened
+	if len(cfg.MFAKeys) == 0 {
+		cfg.MFAKeys = cfg.SessionKeys
+	}
+	mfaSealer, err := sealing.NewSealer(cfg.MFAKeys)
+	if err != nil {
+		log.Fatalf("error constructing MFA sealer: %v", err)
+	}
+	mfaSigner, err := signing.NewSigner("mfa-challenge", cfg.SessionKeys)
+	if err != nil {
+		log.Fatalf("error constructing MFA challenge signer: %v", err)
+	}
+
+	accessTokenSigner, err := newAccessTokenSigner(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing access token signer: %v", err)
+	}
+	refreshTokenStore, err := newRefreshTokenStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing refresh token store: %v", err)
+	}
+	clientStore, err := newClientStore(&cfg, awsSession, userStore)
+	if err != nil {
+		log.Fatalf("error constructing client store: %v", err)
+	}
+	authCodeStore, err := newAuthCodeStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing authorization code store: %v", err)
+	}
+	identityStore, err := newIdentityStore(&cfg, awsSession, userStore)
+	if err != nil {
+		log.Fatalf("error constructing identity store: %v", err)
+	}
+	externalProviders, err := newExternalProviders(&cfg)
+	if err != nil {
+		log.Fatalf("error constructing external providers: %v", err)
+	}
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnOrigins,
+	})
+	if err != nil {
+		log.Fatalf("error constructing WebAuthn relying party: %v", err)
+	}
+	challengeStore, err := newChallengeStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing challenge store: %v", err)
+	}
+
+	throttleStore, err := newThrottleStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing throttle store: %v", err)
+	}
+	userThrottler := throttle.NewThrottler(throttleStore, "signin-user:", throttle.Policy{
+		FreeAttempts: cfg.SignInFreeAttemptsUser,
+		BaseDelay:    cfg.SignInBaseDelay,
+		MaxDelay:     cfg.SignInMaxDelay,
+		Window:       cfg.SignInFailureWindow,
+	})
+	ipThrottler := throttle.NewThrottler(throttleStore, "signin-ip:", throttle.Policy{
+		FreeAttempts: cfg.SignInFreeAttemptsIP,
+		BaseDelay:    cfg.SignInBaseDelay,
+		MaxDelay:     cfg.SignInMaxDelay,
+		Window:       cfg.SignInFailureWindow,
+	})
+
+	//rate limit rules are in the form "<method> <path> <requests>/<period>"
+	var rateLimitRules []*ratelimit.Rule
+	for _, s := range cfg.RateLimits {
+		rule, err := ratelimit.ParseRule(s)
+		if err != nil {
+			log.Fatalf("error parsing rate limits: %v", err)
+		}
+		rateLimitRules = append(rateLimitRules, rule)
+	}
+	rateLimitStore, err := newRateLimitStore(&cfg, redisPool)
+	if err != nil {
+		log.Fatalf("error constructing rate limit store: %v", err)
+	}
 
 	handlerConfig := &handlers.Config{
-		SessionManager: sessions.NewManager(sessions.DefaultIDLength, cfg.SessionKeys, sessionStore),
-		UserStore:      users.NewDynamoDBStore(dynamoClient, cfg.DynamoDBTable, cfg.DynamoDBKey),
+		SessionManager:      sessions.NewManager(sessions.DefaultIDLength, cfg.SessionKeys, sessionStore),
+		UserStore:           userStore,
+		ResetStore:          resetStore,
+		Mailer:              emailer,
+		ResetURL:            cfg.ResetURL,
+		ResetTTL:            cfg.ResetTTL,
+		EmailSigner:         emailSigner,
+		VerifyEmailURL:      cfg.VerifyURL,
+		VerifyEmailTTL:      cfg.VerifyTTL,
+		SMSCodeStore:        smsCodeStore,
+		SMSSender:           smsSender,
+		SMSCodeTTL:          cfg.SMSCodeTTL,
+		MFASealer:           mfaSealer,
+		MFAIssuer:           cfg.MFAIssuer,
+		MFASigner:           mfaSigner,
+		MFAChallengeTTL:     cfg.MFAChallengeTTL,
+		WebAuthn:            webAuthn,
+		ChallengeStore:      challengeStore,
+		PasskeyChallengeTTL: cfg.PasskeyChallengeTTL,
+		UserThrottler:       userThrottler,
+		IPThrottler:         ipThrottler,
+		RateLimitRules:      rateLimitRules,
+		RateLimitStore:      rateLimitStore,
+		SessionStore:        sessionStore,
+		SessionIndex:        sessionIndex,
+		SessionLifetime: handlers.SessionLifetime{
+			IdleTimeout: cfg.SessionIdleTimeout,
+			MaxAge:      cfg.SessionMaxAge,
+		},
+		RememberedSessionLifetime: handlers.SessionLifetime{
+			IdleTimeout: cfg.RememberIdleTimeout,
+			MaxAge:      cfg.RememberMaxAge,
+		},
+		AccessTokenSigner:   accessTokenSigner,
+		TokenIssuer:         cfg.TokenIssuer,
+		AccessTokenTTL:      cfg.AccessTokenTTL,
+		RefreshTokenStore:   refreshTokenStore,
+		RefreshTokenTTL:     cfg.RefreshTokenTTL,
+		ClientStore:         clientStore,
+		AuthCodeStore:       authCodeStore,
+		AuthCodeTTL:         cfg.AuthCodeTTL,
+		SignInURL:           cfg.SignInURL,
+		ExternalProviders:   externalProviders,
+		IdentityStore:       identityStore,
+		ExternalSignInTTL:   cfg.ExternalSignInTTL,
+		Authenticator:       authenticator,
+		DeletionGracePeriod: cfg.DeletionGracePeriod,
+		SMSSendLimit: ratelimit.Limit{
+			Requests: cfg.SMSSendLimit,
+			Period:   cfg.SMSSendPeriod,
+		},
+		RefreshFamilyMaxAge: cfg.RefreshFamilyMaxAge,
+		SignUpDisabled:      cfg.Authenticator == "ldap",
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handlers.RootHandler)
 	mux.HandleFunc("/users", handlerConfig.UsersHandler)
 	mux.HandleFunc("/users/", handlerConfig.EnsureSession(handlerConfig.SpecificUserHandler))
+	mux.HandleFunc("/users/me/password", handlerConfig.EnsureSession(handlerConfig.PasswordHandler))
+	mux.HandleFunc("/users/me/email-verifications", handlerConfig.EnsureSession(handlerConfig.EmailVerificationsHandler))
+	mux.HandleFunc("/email-verifications/", handlerConfig.SpecificEmailVerificationHandler)
+	mux.HandleFunc("/users/me/mobile-verifications", handlerConfig.EnsureSession(handlerConfig.MobileVerificationsHandler))
+	mux.HandleFunc("/users/me/mobile-verifications/confirmation",
+		handlerConfig.EnsureSession(handlerConfig.MobileVerificationConfirmationHandler))
+	mux.HandleFunc("/users/me/mfa/totp", handlerConfig.EnsureSession(handlerConfig.TOTPHandler))
+	mux.HandleFunc("/users/me/mfa/totp/confirmation", handlerConfig.EnsureSession(handlerConfig.TOTPConfirmationHandler))
+	mux.HandleFunc("/users/me/passkeys", handlerConfig.EnsureSession(handlerConfig.PasskeysHandler))
+	mux.HandleFunc("/users/me/passkeys/", handlerConfig.EnsureSession(handlerConfig.SpecificPasskeyHandler))
+	mux.HandleFunc("/users/me/passkeys/challenges", handlerConfig.EnsureSession(handlerConfig.PasskeyChallengesHandler))
+	mux.HandleFunc("/users/me/identities", handlerConfig.EnsureSession(handlerConfig.IdentitiesHandler))
+	mux.HandleFunc("/users/me/identities/", handlerConfig.EnsureSession(handlerConfig.SpecificIdentityHandler))
 	mux.HandleFunc("/sessions", handlerConfig.SessionsHandler)
+	mux.HandleFunc("/sessions/mfa", handlerConfig.SessionsMFAHandler)
+	mux.HandleFunc("/sessions/passkey", handlerConfig.SessionsPasskeyHandler)
+	mux.HandleFunc("/sessions/passkey/challenges", handlerConfig.SessionsPasskeyChallengesHandler)
 	mux.HandleFunc("/sessions/mine", handlerConfig.SessionsMineHandler)
+	mux.HandleFunc("/sessions/external", handlerConfig.ExternalSessionsHandler)
+	mux.HandleFunc("/sessions/external/callback", handlerConfig.ExternalSessionsCallbackHandler)
+	mux.HandleFunc("/sessions/", handlerConfig.EnsureSession(handlerConfig.SpecificSessionHandler))
+	mux.HandleFunc("/tokens", handlerConfig.TokensHandler)
+	mux.HandleFunc("/oauth/authorize", handlerConfig.AuthorizeHandler)
+	mux.HandleFunc("/oauth/userinfo", handlerConfig.UserInfoHandler)
+	mux.HandleFunc("/oauth/clients", handlerConfig.EnsureSession(handlerConfig.OAuthClientsHandler))
+	mux.HandleFunc("/oauth/clients/", handlerConfig.EnsureSession(handlerConfig.SpecificOAuthClientHandler))
+	mux.HandleFunc("/.well-known/openid-configuration", handlerConfig.DiscoveryHandler)
+	mux.HandleFunc("/.well-known/jwks.json", handlerConfig.JWKSHandler)
+	mux.HandleFunc("/admin/users/", handlerConfig.AdminUsersHandler)
+	mux.HandleFunc("/account-restorations", handlerConfig.AccountRestorationsHandler)
+	mux.HandleFunc("/password-resets", handlerConfig.PasswordResetsHandler)
+	mux.HandleFunc("/password-resets/", handlerConfig.SpecificPasswordResetHandler)
+
+	go handlerConfig.RunPurger(cfg.PurgeInterval)
 
 	log.Printf("server is listening at http://%s...", cfg.Addr)
-	log.Fatal(http.ListenAndServe(cfg.Addr, mux))
+	log.Fatal(http.ListenAndServe(cfg.Addr, handlerConfig.RateLimit(mux)))
 
 }
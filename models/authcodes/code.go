@@ -0,0 +1,78 @@
+//Package authcodes stores the single-use OAuth authorization codes
+//issued to clients at the end of the authorization-code flow
+package authcodes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+//codeLength is the number of random bytes in an authorization code
+const codeLength = 32
+
+//MethodS256 is the only supported PKCE code challenge method
+const MethodS256 = "S256"
+
+//Code represents a single-use authorization code.
+//Only the hash of the code is stored.
+type Code struct {
+	Hash        string `json:"hash"`
+	ClientID    string `json:"clientID"`
+	UserName    string `json:"userName"`
+	RedirectURI string `json:"redirectURI"`
+	Scope       string `json:"scope"`
+	Nonce       string `json:"nonce,omitempty"`
+	//CodeChallenge is the PKCE code challenge, which the client must
+	//prove it created by supplying the verifier when redeeming the code
+	CodeChallenge string `json:"codeChallenge"`
+	//AuthTime is when the user signed in
+	AuthTime time.Time `json:"authTime"`
+	Expires  time.Time `json:"expires"`
+}
+
+//NewCode generates a new authorization code from template that expires
+//after ttl. It returns the plaintext code, which should be sent to the
+//client, and the Code, which should be inserted into a Store.
+func NewCode(template *Code, ttl time.Duration) (string, *Code, error) {
+	buf := make([]byte, codeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("error generating random code: %v", err)
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+	code := *template
+	code.Hash = HashCode(plaintext)
+	code.Expires = time.Now().Add(ttl)
+	return plaintext, &code, nil
+}
+
+//HashCode returns the hash of a plaintext code
+func HashCode(plaintext string) string {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Expired returns true if the code has expired
+func (c *Code) Expired() bool {
+	return time.Now().After(c.Expires)
+}
+
+//S256Challenge returns the S256 PKCE code challenge for verifier
+func S256Challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+//VerifyChallenge returns true if verifier is the PKCE
+//code verifier from which the code challenge was derived
+func (c *Code) VerifyChallenge(verifier string) bool {
+	//RFC 7636 requires 43-128 characters
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(c.CodeChallenge)) == 1
+}
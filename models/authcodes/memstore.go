@@ -0,0 +1,41 @@
+package authcodes
+
+import "sync"
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx    sync.Mutex
+	codes map[string]*Code
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		codes: map[string]*Code{},
+	}
+}
+
+//Insert inserts a new code
+func (ms *MemStore) Insert(code *Code) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	stored := *code
+	ms.codes[code.Hash] = &stored
+	return nil
+}
+
+//Take gets and deletes the code with the given hash
+func (ms *MemStore) Take(hash string) (*Code, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	code, found := ms.codes[hash]
+	if !found {
+		return nil, ErrNotFound
+	}
+	delete(ms.codes, hash)
+	if code.Expired() {
+		return nil, ErrNotFound
+	}
+	return code, nil
+}
@@ -0,0 +1,64 @@
+package authcodes
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyChallenge(t *testing.T) {
+	verifier := strings.Repeat("v", 43)
+	code := &Code{CodeChallenge: S256Challenge(verifier)}
+	if !code.VerifyChallenge(verifier) {
+		t.Errorf("correct verifier was rejected")
+	}
+	if code.VerifyChallenge(strings.Repeat("x", 43)) {
+		t.Errorf("incorrect verifier was accepted")
+	}
+	short := "short"
+	code.CodeChallenge = S256Challenge(short)
+	if code.VerifyChallenge(short) {
+		t.Errorf("verifier shorter than 43 characters was accepted")
+	}
+
+	//test vector from RFC 7636 appendix B
+	if got := S256Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("incorrect S256 challenge: got %s", got)
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+
+	template := &Code{ClientID: "client", UserName: "tester", RedirectURI: "https://app.example.com/cb"}
+	plaintext, code, err := NewCode(template, time.Minute)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+	if code.Hash == plaintext {
+		t.Fatalf("code hash must not equal the plaintext code")
+	}
+	if len(template.Hash) > 0 {
+		t.Errorf("NewCode must not modify the template")
+	}
+	if err := store.Insert(code); err != nil {
+		t.Fatalf("error inserting code: %v", err)
+	}
+
+	got, err := store.Take(HashCode(plaintext))
+	if err != nil {
+		t.Fatalf("error taking code: %v", err)
+	}
+	if got.UserName != "tester" || got.ClientID != "client" {
+		t.Errorf("incorrect code: %+v", got)
+	}
+	if _, err := store.Take(HashCode(plaintext)); err != ErrNotFound {
+		t.Errorf("incorrect error taking a used code: expected %v but got %v", ErrNotFound, err)
+	}
+
+	_, expired, _ := NewCode(template, -time.Minute)
+	store.Insert(expired)
+	if _, err := store.Take(expired.Hash); err != ErrNotFound {
+		t.Errorf("incorrect error taking an expired code: expected %v but got %v", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,75 @@
+package authcodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to code hashes to form redis keys
+const redisKeyPrefix = "authcode:"
+
+//RedisStore is an implementation of the Store interface for redis.
+//Codes are stored with a redis TTL so they are removed once they expire.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Insert inserts a new code
+func (rs *RedisStore) Insert(code *Code) error {
+	ttl := time.Until(code.Expires)
+	if ttl <= 0 {
+		return fmt.Errorf("code has already expired")
+	}
+	j, err := json.Marshal(code)
+	if err != nil {
+		return fmt.Errorf("error encoding code: %v", err)
+	}
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", redisKeyPrefix+code.Hash, j, "PX", ttl.Nanoseconds()/int64(time.Millisecond)); err != nil {
+		return fmt.Errorf("error inserting code: %v", err)
+	}
+	return nil
+}
+
+//Take gets and deletes the code with the given hash
+func (rs *RedisStore) Take(hash string) (*Code, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	//GET and DEL within a transaction so that
+	//concurrent requests can't both use the code
+	key := redisKeyPrefix + hash
+	conn.Send("MULTI")
+	conn.Send("GET", key)
+	conn.Send("DEL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting code: %v", err)
+	}
+	j, err := redis.Bytes(replies[0], nil)
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting code: %v", err)
+	}
+	code := &Code{}
+	if err := json.Unmarshal(j, code); err != nil {
+		return nil, fmt.Errorf("error decoding code: %v", err)
+	}
+	if code.Expired() {
+		return nil, ErrNotFound
+	}
+	return code, nil
+}
@@ -0,0 +1,16 @@
+package authcodes
+
+import "errors"
+
+//ErrNotFound is returned from Store.Take when the code doesn't exist,
+//has already been used, or has expired
+var ErrNotFound = errors.New("authorization code not found or expired")
+
+//Store describes what an authorization code store can do
+type Store interface {
+	//Insert inserts a new code
+	Insert(code *Code) error
+	//Take atomically gets and deletes the code with the given hash,
+	//so that each code can be used only once
+	Take(hash string) (*Code, error)
+}
@@ -0,0 +1,69 @@
+//Package challenges stores the server-side state of multi-step
+//ceremonies, such as WebAuthn registration and sign-in, between
+//the request that begins the ceremony and the one that completes it
+package challenges
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//idLength is the number of random bytes in a challenge ID
+const idLength = 32
+
+//Challenge is the state of a pending ceremony. Only the hash of its ID
+//is stored, so that someone with read access to the store can't
+//complete ceremonies on behalf of users.
+type Challenge struct {
+	Hash string `json:"hash"`
+	//UserName is the user who began the ceremony, if signed in
+	UserName string `json:"userName,omitempty"`
+	//Data is the ceremony-specific state
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires"`
+}
+
+//NewChallenge generates a new challenge holding data for userName that
+//expires after ttl. It returns the plaintext ID, which should be sent
+//to the client, and the Challenge, which should be inserted into a Store.
+func NewChallenge(userName string, data interface{}, ttl time.Duration) (string, *Challenge, error) {
+	j, err := json.Marshal(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("error encoding challenge data: %v", err)
+	}
+	buf := make([]byte, idLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("error generating random challenge ID: %v", err)
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+	return plaintext, &Challenge{
+		Hash:     HashID(plaintext),
+		UserName: userName,
+		Data:     j,
+		Expires:  time.Now().Add(ttl),
+	}, nil
+}
+
+//HashID returns the hash of a plaintext challenge ID
+func HashID(plaintext string) string {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Expired returns true if the challenge has expired
+func (c *Challenge) Expired() bool {
+	return time.Now().After(c.Expires)
+}
+
+//Decode decodes the challenge's data into target
+func (c *Challenge) Decode(target interface{}) error {
+	if err := json.Unmarshal(c.Data, target); err != nil {
+		return fmt.Errorf("error decoding challenge data: %v", err)
+	}
+	return nil
+}
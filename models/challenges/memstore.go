@@ -0,0 +1,41 @@
+package challenges
+
+import "sync"
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx         sync.Mutex
+	challenges map[string]*Challenge
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		challenges: map[string]*Challenge{},
+	}
+}
+
+//Insert inserts a new challenge
+func (ms *MemStore) Insert(challenge *Challenge) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	stored := *challenge
+	ms.challenges[challenge.Hash] = &stored
+	return nil
+}
+
+//Take gets and deletes the challenge with the given hash
+func (ms *MemStore) Take(hash string) (*Challenge, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	challenge, found := ms.challenges[hash]
+	if !found {
+		return nil, ErrNotFound
+	}
+	delete(ms.challenges, hash)
+	if challenge.Expired() {
+		return nil, ErrNotFound
+	}
+	return challenge, nil
+}
@@ -0,0 +1,51 @@
+package challenges
+
+import (
+	"testing"
+	"time"
+)
+
+type testData struct {
+	Value string `json:"value"`
+}
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+
+	plaintext, challenge, err := NewChallenge("tester", &testData{Value: "state"}, time.Hour)
+	if err != nil {
+		t.Fatalf("error generating challenge: %v", err)
+	}
+	if challenge.Hash == plaintext {
+		t.Fatalf("challenge hash must not equal the plaintext ID")
+	}
+	if err := store.Insert(challenge); err != nil {
+		t.Fatalf("error inserting challenge: %v", err)
+	}
+
+	got, err := store.Take(HashID(plaintext))
+	if err != nil {
+		t.Fatalf("error taking challenge: %v", err)
+	}
+	if got.UserName != "tester" {
+		t.Errorf("incorrect userName: expected tester but got %s", got.UserName)
+	}
+	data := &testData{}
+	if err := got.Decode(data); err != nil {
+		t.Errorf("error decoding challenge data: %v", err)
+	} else if data.Value != "state" {
+		t.Errorf("incorrect challenge data: expected state but got %s", data.Value)
+	}
+	if _, err := store.Take(HashID(plaintext)); err != ErrNotFound {
+		t.Errorf("incorrect error taking a used challenge: expected %v but got %v", ErrNotFound, err)
+	}
+
+	_, expired, err := NewChallenge("tester", &testData{}, -time.Minute)
+	if err != nil {
+		t.Fatalf("error generating challenge: %v", err)
+	}
+	store.Insert(expired)
+	if _, err := store.Take(expired.Hash); err != ErrNotFound {
+		t.Errorf("incorrect error taking an expired challenge: expected %v but got %v", ErrNotFound, err)
+	}
+}
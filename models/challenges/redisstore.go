@@ -0,0 +1,75 @@
+package challenges
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to challenge hashes to form redis keys
+const redisKeyPrefix = "challenge:"
+
+//RedisStore is an implementation of the Store interface for redis.
+//Challenges are stored with a redis TTL so they are removed once they expire.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Insert inserts a new challenge
+func (rs *RedisStore) Insert(challenge *Challenge) error {
+	ttl := time.Until(challenge.Expires)
+	if ttl <= 0 {
+		return fmt.Errorf("challenge has already expired")
+	}
+	j, err := json.Marshal(challenge)
+	if err != nil {
+		return fmt.Errorf("error encoding challenge: %v", err)
+	}
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", redisKeyPrefix+challenge.Hash, j, "PX", ttl.Nanoseconds()/int64(time.Millisecond)); err != nil {
+		return fmt.Errorf("error inserting challenge: %v", err)
+	}
+	return nil
+}
+
+//Take gets and deletes the challenge with the given hash
+func (rs *RedisStore) Take(hash string) (*Challenge, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	//GET and DEL within a transaction so that
+	//concurrent requests can't both use the challenge
+	key := redisKeyPrefix + hash
+	conn.Send("MULTI")
+	conn.Send("GET", key)
+	conn.Send("DEL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting challenge: %v", err)
+	}
+	j, err := redis.Bytes(replies[0], nil)
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting challenge: %v", err)
+	}
+	challenge := &Challenge{}
+	if err := json.Unmarshal(j, challenge); err != nil {
+		return nil, fmt.Errorf("error decoding challenge: %v", err)
+	}
+	if challenge.Expired() {
+		return nil, ErrNotFound
+	}
+	return challenge, nil
+}
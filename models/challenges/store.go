@@ -0,0 +1,16 @@
+package challenges
+
+import "errors"
+
+//ErrNotFound is returned from Store.Take when the challenge doesn't exist,
+//has already been used, or has expired
+var ErrNotFound = errors.New("challenge not found or expired")
+
+//Store describes what a challenge store can do
+type Store interface {
+	//Insert inserts a new challenge
+	Insert(challenge *Challenge) error
+	//Take atomically gets and deletes the challenge with the given hash,
+	//so that each challenge can be used only once
+	Take(hash string) (*Challenge, error)
+}
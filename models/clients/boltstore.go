@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+//boltClientsBucket is the name of the bucket holding clients
+var boltClientsBucket = []byte("clients")
+
+//BoltStore is an implementation of the Store interface for bbolt. Only one
+//process may have a bbolt database file open at a time, so it shares the
+//database opened by the users.BoltStore.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+//NewBoltStore constructs a new BoltStore that uses db
+func NewBoltStore(db *bolt.DB) (*BoltStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltClientsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating clients bucket: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+//Insert inserts a new client
+func (bs *BoltStore) Insert(client *Client) error {
+	j, err := json.Marshal(client)
+	if err != nil {
+		return fmt.Errorf("error encoding client: %v", err)
+	}
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltClientsBucket).Put([]byte(client.ID), j)
+	})
+	if err != nil {
+		return fmt.Errorf("error inserting client: %v", err)
+	}
+	return nil
+}
+
+//Get gets the client with the given ID
+func (bs *BoltStore) Get(id string) (*Client, error) {
+	var j []byte
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		//values are only valid during the transaction
+		if val := tx.Bucket(boltClientsBucket).Get([]byte(id)); val != nil {
+			j = append([]byte(nil), val...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %v", err)
+	}
+	if j == nil {
+		return nil, ErrNotFound
+	}
+	client := &Client{}
+	if err := json.Unmarshal(j, client); err != nil {
+		return nil, fmt.Errorf("error decoding client: %v", err)
+	}
+	return client, nil
+}
+
+//List lists the clients owned by owner, oldest first.
+//This reads every client, as bbolt has no secondary indexes.
+func (bs *BoltStore) List(owner string) ([]*Client, error) {
+	owned := []*Client{}
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltClientsBucket).ForEach(func(k []byte, v []byte) error {
+			client := &Client{}
+			if err := json.Unmarshal(v, client); err != nil {
+				return fmt.Errorf("error decoding client: %v", err)
+			}
+			if client.Owner == owner {
+				owned = append(owned, client)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing clients: %v", err)
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].Created.Before(owned[j].Created)
+	})
+	return owned, nil
+}
+
+//Delete deletes the client with the given ID
+func (bs *BoltStore) Delete(id string) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltClientsBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+	if err == ErrNotFound {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting client: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+//Package clients stores the OAuth/OpenID Connect client
+//applications that may use this service as their identity provider
+package clients
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+//secretLength is the number of random bytes in client IDs and secrets
+const secretLength = 32
+
+//Client is a registered client application. Only the hash of a
+//confidential client's secret is stored. Public clients, such as
+//mobile and single-page apps, have no secret.
+type Client struct {
+	ID           string    `json:"id"`
+	SecretHash   string    `json:"secretHash,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectURIs"`
+	Owner        string    `json:"owner"`
+	Created      time.Time `json:"created"`
+}
+
+//Registration is a request to register a new client
+type Registration struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectURIs"`
+	//Public is true for clients that can't keep a secret
+	Public bool `json:"public"`
+}
+
+//Validate validates the Registration
+func (reg *Registration) Validate() error {
+	if len(reg.Name) == 0 {
+		return fmt.Errorf("name must be supplied")
+	}
+	if len(reg.RedirectURIs) == 0 {
+		return fmt.Errorf("at least one redirect URI must be supplied")
+	}
+	for _, uri := range reg.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//validateRedirectURI ensures uri is an absolute URI without a fragment,
+//which uses https unless it refers to the local machine
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || len(u.Host) == 0 {
+		return fmt.Errorf("redirect URI '%s' must be an absolute URI", uri)
+	}
+	if len(u.Fragment) > 0 {
+		return fmt.Errorf("redirect URI '%s' must not contain a fragment", uri)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && (u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1")) {
+		return fmt.Errorf("redirect URI '%s' must use https", uri)
+	}
+	return nil
+}
+
+//ToClient converts the Registration to a Client owned by owner. It returns
+//the plaintext secret, which is empty for public clients and should be
+//sent to the owner, and the Client, which should be inserted into a Store.
+func (reg *Registration) ToClient(owner string) (string, *Client, error) {
+	id, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	client := &Client{
+		ID:           id,
+		Name:         reg.Name,
+		RedirectURIs: reg.RedirectURIs,
+		Owner:        owner,
+		Created:      time.Now().UTC(),
+	}
+	if reg.Public {
+		return "", client, nil
+	}
+	secret, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	client.SecretHash = hashSecret(secret)
+	return secret, client, nil
+}
+
+//randomString returns a random, URL-safe string
+func randomString() (string, error) {
+	buf := make([]byte, secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+//hashSecret returns the hash of a plaintext client secret
+func hashSecret(plaintext string) string {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Public returns true if the client has no secret
+func (c *Client) Public() bool {
+	return len(c.SecretHash) == 0
+}
+
+//Authenticate returns true if secret is the client's secret
+func (c *Client) Authenticate(secret string) bool {
+	if c.Public() {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(c.SecretHash)) == 1
+}
+
+//AllowsRedirect returns true if uri exactly matches
+//one of the client's registered redirect URIs
+func (c *Client) AllowsRedirect(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if uri == allowed {
+			return true
+		}
+	}
+	return false
+}
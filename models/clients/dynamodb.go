@@ -0,0 +1,117 @@
+package clients
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//DynamoDBStore is an implementation of the Store interface for AWS DynamoDB
+type DynamoDBStore struct {
+	client    *dynamodb.DynamoDB
+	tableName string
+}
+
+//NewDynamoDBStore constructs a new DynamoDBStore. The table identified by
+//tableName should already exist, with a string partition key named "id".
+func NewDynamoDBStore(client *dynamodb.DynamoDB, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+//Insert inserts a new client
+func (d *DynamoDBStore) Insert(client *Client) error {
+	vals, err := dynamodbattribute.MarshalMap(client)
+	if err != nil {
+		return fmt.Errorf("error encoding client: %v", err)
+	}
+	_, err = d.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      vals,
+	})
+	if err != nil {
+		return fmt.Errorf("error inserting client: %v", err)
+	}
+	return nil
+}
+
+//Get gets the client with the given ID
+func (d *DynamoDBStore) Get(id string) (*Client, error) {
+	result, err := d.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getKey(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %v", err)
+	}
+	if result.Item == nil {
+		return nil, ErrNotFound
+	}
+	client := &Client{}
+	if err := dynamodbattribute.UnmarshalMap(result.Item, client); err != nil {
+		return nil, fmt.Errorf("error decoding client: %v", err)
+	}
+	return client, nil
+}
+
+//List lists the clients owned by owner, oldest first. The table has
+//no index on owner, so this scans the table; clients are listed only
+//when their owners manage them, so this should be infrequent.
+func (d *DynamoDBStore) List(owner string) ([]*Client, error) {
+	owned := []*Client{}
+	var decodeErr error
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(d.tableName),
+		FilterExpression:          aws.String("#owner = :owner"),
+		ExpressionAttributeNames:  map[string]*string{"#owner": aws.String("owner")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":owner": {S: aws.String(owner)}},
+	}
+	err := d.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		pageClients := []*Client{}
+		if decodeErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageClients); decodeErr != nil {
+			return false
+		}
+		owned = append(owned, pageClients...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing clients: %v", err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("error decoding client: %v", decodeErr)
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].Created.Before(owned[j].Created)
+	})
+	return owned, nil
+}
+
+//Delete deletes the client with the given ID
+func (d *DynamoDBStore) Delete(id string) error {
+	_, err := d.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                aws.String(d.tableName),
+		Key:                      d.getKey(id),
+		ConditionExpression:      aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("id")},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting client: %v", err)
+	}
+	return nil
+}
+
+//getKey returns the key of the item for the client with the given ID
+func (d *DynamoDBStore) getKey(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String(id)},
+	}
+}
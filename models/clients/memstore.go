@@ -0,0 +1,73 @@
+package clients
+
+import (
+	"sort"
+	"sync"
+)
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx      sync.Mutex
+	clients map[string]*Client
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		clients: map[string]*Client{},
+	}
+}
+
+//copyClient returns a copy of client that shares no memory with it
+func copyClient(client *Client) *Client {
+	c := *client
+	c.RedirectURIs = append([]string(nil), client.RedirectURIs...)
+	return &c
+}
+
+//Insert inserts a new client
+func (ms *MemStore) Insert(client *Client) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	ms.clients[client.ID] = copyClient(client)
+	return nil
+}
+
+//Get gets the client with the given ID
+func (ms *MemStore) Get(id string) (*Client, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	client, found := ms.clients[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyClient(client), nil
+}
+
+//List lists the clients owned by owner, oldest first
+func (ms *MemStore) List(owner string) ([]*Client, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	owned := []*Client{}
+	for _, client := range ms.clients {
+		if client.Owner == owner {
+			owned = append(owned, copyClient(client))
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].Created.Before(owned[j].Created)
+	})
+	return owned, nil
+}
+
+//Delete deletes the client with the given ID
+func (ms *MemStore) Delete(id string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, found := ms.clients[id]; !found {
+		return ErrNotFound
+	}
+	delete(ms.clients, id)
+	return nil
+}
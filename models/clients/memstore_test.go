@@ -0,0 +1,35 @@
+package clients
+
+import (
+	"testing"
+)
+
+func TestRegistration(t *testing.T) {
+	cases := []struct {
+		name  string
+		reg   *Registration
+		valid bool
+	}{
+		{"valid", &Registration{Name: "app", RedirectURIs: []string{"https://app.example.com/cb"}}, true},
+		{"localhost", &Registration{Name: "app", RedirectURIs: []string{"http://localhost:8080/cb"}}, true},
+		{"custom scheme", &Registration{Name: "app", RedirectURIs: []string{"com.example.app://cb"}}, false},
+		{"missing name", &Registration{RedirectURIs: []string{"https://app.example.com/cb"}}, false},
+		{"no redirect URIs", &Registration{Name: "app"}, false},
+		{"relative", &Registration{Name: "app", RedirectURIs: []string{"/cb"}}, false},
+		{"http", &Registration{Name: "app", RedirectURIs: []string{"http://app.example.com/cb"}}, false},
+		{"fragment", &Registration{Name: "app", RedirectURIs: []string{"https://app.example.com/cb#x"}}, false},
+	}
+	for _, c := range cases {
+		err := c.reg.Validate()
+		if c.valid && err != nil {
+			t.Errorf("case %s: unexpected error: %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("case %s: expected an error but didn't get one", c.name)
+		}
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+//pgClientColumns are the columns of the clients table
+const pgClientColumns = "id, secret_hash, name, redirect_uris, owner, created"
+
+//PostgresStore is an implementation of the Store interface for PostgreSQL.
+//The clients table is created by the users.PostgresStore's migrations,
+//so that store must be constructed first.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+//NewPostgresStore constructs a new PostgresStore that uses db
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+//pgScanner is implemented by both sql.Row and sql.Rows
+type pgScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+//scanClient scans a row containing the pgClientColumns
+func scanClient(row pgScanner) (*Client, error) {
+	client := &Client{}
+	err := row.Scan(&client.ID, &client.SecretHash, &client.Name,
+		pq.Array(&client.RedirectURIs), &client.Owner, &client.Created)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+//Insert inserts a new client
+func (ps *PostgresStore) Insert(client *Client) error {
+	_, err := ps.db.Exec("INSERT INTO clients ("+pgClientColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		client.ID, client.SecretHash, client.Name, pq.Array(client.RedirectURIs), client.Owner, client.Created)
+	if err != nil {
+		return fmt.Errorf("error inserting client: %v", err)
+	}
+	return nil
+}
+
+//Get gets the client with the given ID
+func (ps *PostgresStore) Get(id string) (*Client, error) {
+	client, err := scanClient(ps.db.QueryRow("SELECT "+pgClientColumns+" FROM clients WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %v", err)
+	}
+	return client, nil
+}
+
+//List lists the clients owned by owner, oldest first
+func (ps *PostgresStore) List(owner string) ([]*Client, error) {
+	rows, err := ps.db.Query("SELECT "+pgClientColumns+" FROM clients WHERE owner = $1 ORDER BY created", owner)
+	if err != nil {
+		return nil, fmt.Errorf("error listing clients: %v", err)
+	}
+	defer rows.Close()
+	owned := []*Client{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error listing clients: %v", err)
+		}
+		owned = append(owned, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing clients: %v", err)
+	}
+	return owned, nil
+}
+
+//Delete deletes the client with the given ID
+func (ps *PostgresStore) Delete(id string) error {
+	result, err := ps.db.Exec("DELETE FROM clients WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting client: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error deleting client: %v", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package clients
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/davestearns/userservice/models/users"
+	_ "github.com/lib/pq"
+)
+
+//TestPostgresStore runs against the database identified by the
+//POSTGRES_TEST_DSN environment variable, and is skipped if that is not set
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if len(dsn) == 0 {
+		t.Skip("skipping PostgreSQL tests: POSTGRES_TEST_DSN not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	//the users store's migrations create the clients table
+	if _, err := users.NewPostgresStore(db); err != nil {
+		t.Fatalf("error migrating database: %v", err)
+	}
+	testStore(t, NewPostgresStore(db))
+}
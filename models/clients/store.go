@@ -0,0 +1,18 @@
+package clients
+
+import "errors"
+
+//ErrNotFound is returned when the requested client doesn't exist
+var ErrNotFound = errors.New("client not found")
+
+//Store describes what a client store can do
+type Store interface {
+	//Insert inserts a new client
+	Insert(client *Client) error
+	//Get gets the client with the given ID
+	Get(id string) (*Client, error)
+	//List lists the clients owned by owner, oldest first
+	List(owner string) ([]*Client, error)
+	//Delete deletes the client with the given ID
+	Delete(id string) error
+}
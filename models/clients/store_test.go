@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//testStore runs the conformance tests that every Store implementation must pass
+func testStore(t *testing.T, store Store) {
+	owner := fmt.Sprintf("tester-%d", time.Now().UnixNano())
+
+	reg := &Registration{Name: "app", RedirectURIs: []string{"https://app.example.com/cb"}}
+	secret, client, err := reg.ToClient(owner)
+	if err != nil {
+		t.Fatalf("error converting registration: %v", err)
+	}
+	if client.Public() || len(secret) == 0 {
+		t.Fatalf("confidential client must have a secret")
+	}
+	if err := store.Insert(client); err != nil {
+		t.Fatalf("error inserting client: %v", err)
+	}
+
+	got, err := store.Get(client.ID)
+	if err != nil {
+		t.Fatalf("error getting client: %v", err)
+	}
+	if !got.Authenticate(secret) {
+		t.Errorf("client didn't authenticate with its secret")
+	}
+	if got.Authenticate("wrong") {
+		t.Errorf("client authenticated with the wrong secret")
+	}
+	if !got.AllowsRedirect("https://app.example.com/cb") || got.AllowsRedirect("https://app.example.com/other") {
+		t.Errorf("incorrect redirect URI matching")
+	}
+
+	publicReg := &Registration{Name: "mobile", RedirectURIs: []string{"http://127.0.0.1/cb"}, Public: true}
+	secret, public, _ := publicReg.ToClient(owner)
+	if !public.Public() || len(secret) > 0 {
+		t.Errorf("public client must not have a secret")
+	}
+	public.Created = client.Created.Add(time.Second)
+	store.Insert(public)
+
+	owned, err := store.List(owner)
+	if err != nil {
+		t.Fatalf("error listing clients: %v", err)
+	}
+	if len(owned) != 2 {
+		t.Fatalf("incorrect number of clients: expected 2 but got %d", len(owned))
+	}
+	if owned[0].ID != client.ID || owned[1].ID != public.ID {
+		t.Errorf("clients were not listed oldest first")
+	}
+	if !reflect.DeepEqual(owned[1].RedirectURIs, publicReg.RedirectURIs) {
+		t.Errorf("incorrect redirect URIs: expected %v but got %v", publicReg.RedirectURIs, owned[1].RedirectURIs)
+	}
+
+	if err := store.Delete(client.ID); err != nil {
+		t.Fatalf("error deleting client: %v", err)
+	}
+	if _, err := store.Get(client.ID); err != ErrNotFound {
+		t.Errorf("incorrect error getting deleted client: expected %v but got %v", ErrNotFound, err)
+	}
+	if err := store.Delete(client.ID); err != ErrNotFound {
+		t.Errorf("incorrect error deleting deleted client: expected %v but got %v", ErrNotFound, err)
+	}
+}
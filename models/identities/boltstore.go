@@ -0,0 +1,148 @@
+package identities
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+//boltLinksBucket is the name of the bucket holding links
+var boltLinksBucket = []byte("identities")
+
+//BoltStore is an implementation of the Store interface for bbolt. Only one
+//process may have a bbolt database file open at a time, so it shares the
+//database opened by the users.BoltStore.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+//NewBoltStore constructs a new BoltStore that uses db
+func NewBoltStore(db *bolt.DB) (*BoltStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltLinksBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating identities bucket: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+//Insert inserts a new link
+func (bs *BoltStore) Insert(link *Link) error {
+	j, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("error encoding link: %v", err)
+	}
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLinksBucket)
+		if bucket.Get([]byte(link.ID)) != nil {
+			return ErrAlreadyLinked
+		}
+		return bucket.Put([]byte(link.ID), j)
+	})
+	if err == ErrAlreadyLinked {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error inserting link: %v", err)
+	}
+	return nil
+}
+
+//Get gets the link for the identity with the issuer and subject
+func (bs *BoltStore) Get(issuer string, subject string) (*Link, error) {
+	var link *Link
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		link, err = boltGetLink(tx, LinkID(issuer, subject))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+//List lists the user's links, oldest first.
+//This reads every link, as bbolt has no secondary indexes.
+func (bs *BoltStore) List(userName string) ([]*Link, error) {
+	links := []*Link{}
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLinksBucket).ForEach(func(k []byte, v []byte) error {
+			link := &Link{}
+			if err := json.Unmarshal(v, link); err != nil {
+				return fmt.Errorf("error decoding link: %v", err)
+			}
+			if link.UserName == userName {
+				links = append(links, link)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing links: %v", err)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Linked.Before(links[j].Linked)
+	})
+	return links, nil
+}
+
+//Delete deletes the user's link with the given ID
+func (bs *BoltStore) Delete(userName string, id string) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		link, err := boltGetLink(tx, id)
+		if err != nil {
+			return err
+		}
+		if link.UserName != userName {
+			return ErrNotFound
+		}
+		return tx.Bucket(boltLinksBucket).Delete([]byte(id))
+	})
+	if err == ErrNotFound {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting link: %v", err)
+	}
+	return nil
+}
+
+//DeleteAll deletes all of the user's links
+func (bs *BoltStore) DeleteAll(userName string) error {
+	links, err := bs.List(userName)
+	if err != nil {
+		return err
+	}
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLinksBucket)
+		for _, link := range links {
+			if err := bucket.Delete([]byte(link.ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting links: %v", err)
+	}
+	return nil
+}
+
+//boltGetLink reads and decodes the link with the given ID,
+//returning ErrNotFound if there is no such link
+func boltGetLink(tx *bolt.Tx, id string) (*Link, error) {
+	val := tx.Bucket(boltLinksBucket).Get([]byte(id))
+	if val == nil {
+		return nil, ErrNotFound
+	}
+	link := &Link{}
+	if err := json.Unmarshal(val, link); err != nil {
+		return nil, fmt.Errorf("error decoding link: %v", err)
+	}
+	return link, nil
+}
@@ -0,0 +1,29 @@
+package identities
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identities")
+	if err != nil {
+		t.Fatalf("error creating temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := bolt.Open(filepath.Join(dir, "users.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("error opening bolt database: %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatalf("error creating BoltStore: %v", err)
+	}
+	testStore(t, store)
+}
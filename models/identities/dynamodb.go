@@ -0,0 +1,144 @@
+package identities
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//DynamoDBStore is an implementation of the Store interface for AWS DynamoDB
+type DynamoDBStore struct {
+	client    *dynamodb.DynamoDB
+	tableName string
+}
+
+//NewDynamoDBStore constructs a new DynamoDBStore. The table identified by
+//tableName should already exist, with a string partition key named "id".
+func NewDynamoDBStore(client *dynamodb.DynamoDB, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+//Insert inserts a new link
+func (d *DynamoDBStore) Insert(link *Link) error {
+	vals, err := dynamodbattribute.MarshalMap(link)
+	if err != nil {
+		return fmt.Errorf("error encoding link: %v", err)
+	}
+	_, err = d.client.PutItem(&dynamodb.PutItemInput{
+		TableName:                aws.String(d.tableName),
+		Item:                     vals,
+		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("id")},
+	})
+	if isConditionFailed(err) {
+		return ErrAlreadyLinked
+	}
+	if err != nil {
+		return fmt.Errorf("error inserting link: %v", err)
+	}
+	return nil
+}
+
+//Get gets the link for the identity with the issuer and subject
+func (d *DynamoDBStore) Get(issuer string, subject string) (*Link, error) {
+	result, err := d.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getKey(LinkID(issuer, subject)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting link: %v", err)
+	}
+	if result.Item == nil {
+		return nil, ErrNotFound
+	}
+	link := &Link{}
+	if err := dynamodbattribute.UnmarshalMap(result.Item, link); err != nil {
+		return nil, fmt.Errorf("error decoding link: %v", err)
+	}
+	return link, nil
+}
+
+//List lists the user's links, oldest first. The table has no index
+//on userName, so this scans the table; links are listed only when
+//users manage them or their accounts are purged, which is infrequent.
+func (d *DynamoDBStore) List(userName string) ([]*Link, error) {
+	links := []*Link{}
+	var decodeErr error
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(d.tableName),
+		FilterExpression:          aws.String("#userName = :userName"),
+		ExpressionAttributeNames:  map[string]*string{"#userName": aws.String("userName")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":userName": {S: aws.String(userName)}},
+	}
+	err := d.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		pageLinks := []*Link{}
+		if decodeErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageLinks); decodeErr != nil {
+			return false
+		}
+		links = append(links, pageLinks...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing links: %v", err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("error decoding link: %v", decodeErr)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Linked.Before(links[j].Linked)
+	})
+	return links, nil
+}
+
+//Delete deletes the user's link with the given ID
+func (d *DynamoDBStore) Delete(userName string, id string) error {
+	_, err := d.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 aws.String(d.tableName),
+		Key:                       d.getKey(id),
+		ConditionExpression:       aws.String("#userName = :userName"),
+		ExpressionAttributeNames:  map[string]*string{"#userName": aws.String("userName")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":userName": {S: aws.String(userName)}},
+	})
+	//the condition fails if the link doesn't exist or belongs to another user
+	if isConditionFailed(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting link: %v", err)
+	}
+	return nil
+}
+
+//DeleteAll deletes all of the user's links
+func (d *DynamoDBStore) DeleteAll(userName string) error {
+	links, err := d.List(userName)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err := d.Delete(userName, link.ID); err != nil && err != ErrNotFound {
+			return fmt.Errorf("error deleting links: %v", err)
+		}
+	}
+	return nil
+}
+
+//getKey returns the key of the item for the link with the given ID
+func (d *DynamoDBStore) getKey(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String(id)},
+	}
+}
+
+//isConditionFailed returns true if err is a DynamoDB conditional check failure
+func isConditionFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,19 @@
+package identities
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestDynamoDBStore(t *testing.T) {
+	sess, err := session.NewSession()
+	if err != nil {
+		t.Fatalf("error creating new AWS session: %v", err)
+	}
+	if _, err := sess.Config.Credentials.Get(); err != nil {
+		t.Skipf("skipping DynamoDB tests: no AWS credentials available: %v", err)
+	}
+	testStore(t, NewDynamoDBStore(dynamodb.New(sess), "identity_links"))
+}
@@ -0,0 +1,72 @@
+//Package identities stores the links between users and their
+//identities at external OpenID Connect providers
+package identities
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+//Link links an identity at an external provider, identified by the
+//provider's issuer and the subject it asserts, to a user
+type Link struct {
+	//ID is a public identifier for the link, derived from the issuer and subject
+	ID       string    `json:"id"`
+	Provider string    `json:"provider"`
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	UserName string    `json:"userName"`
+	Email    string    `json:"email,omitempty"`
+	Linked   time.Time `json:"linked"`
+}
+
+//NewLink constructs a new Link
+func NewLink(provider string, issuer string, subject string, userName string, email string) *Link {
+	return &Link{
+		ID:       LinkID(issuer, subject),
+		Provider: provider,
+		Issuer:   issuer,
+		Subject:  subject,
+		UserName: userName,
+		Email:    email,
+		Linked:   time.Now().UTC(),
+	}
+}
+
+//LinkID returns the public ID of the link for issuer and subject
+func LinkID(issuer string, subject string) string {
+	hash := sha256.Sum256([]byte(issuer + "\x00" + subject))
+	return base64.RawURLEncoding.EncodeToString(hash[:16])
+}
+
+//SignInRequest is a request to begin signing in via an external provider
+type SignInRequest struct {
+	Provider string `json:"provider"`
+	//RememberMe selects a longer-lived session
+	RememberMe bool `json:"rememberMe,omitempty"`
+}
+
+//Validate validates the SignInRequest
+func (sr *SignInRequest) Validate() error {
+	if len(sr.Provider) == 0 {
+		return fmt.Errorf("provider must be supplied")
+	}
+	return nil
+}
+
+//Callback holds the parameters with which the
+//provider sent the user back to the redirect page
+type Callback struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+//Validate validates the Callback
+func (cb *Callback) Validate() error {
+	if len(cb.State) == 0 || len(cb.Code) == 0 {
+		return fmt.Errorf("state and code must be supplied")
+	}
+	return nil
+}
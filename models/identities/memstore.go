@@ -0,0 +1,85 @@
+package identities
+
+import (
+	"sort"
+	"sync"
+)
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx    sync.Mutex
+	links map[string]*Link
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		links: map[string]*Link{},
+	}
+}
+
+//Insert inserts a new link
+func (ms *MemStore) Insert(link *Link) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, found := ms.links[link.ID]; found {
+		return ErrAlreadyLinked
+	}
+	stored := *link
+	ms.links[link.ID] = &stored
+	return nil
+}
+
+//Get gets the link for the identity with the issuer and subject
+func (ms *MemStore) Get(issuer string, subject string) (*Link, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	link, found := ms.links[LinkID(issuer, subject)]
+	if !found {
+		return nil, ErrNotFound
+	}
+	got := *link
+	return &got, nil
+}
+
+//List lists the user's links, oldest first
+func (ms *MemStore) List(userName string) ([]*Link, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	links := []*Link{}
+	for _, link := range ms.links {
+		if link.UserName == userName {
+			got := *link
+			links = append(links, &got)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Linked.Before(links[j].Linked)
+	})
+	return links, nil
+}
+
+//Delete deletes the user's link with the given ID
+func (ms *MemStore) Delete(userName string, id string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	link, found := ms.links[id]
+	if !found || link.UserName != userName {
+		return ErrNotFound
+	}
+	delete(ms.links, id)
+	return nil
+}
+
+//DeleteAll deletes all of the user's links
+func (ms *MemStore) DeleteAll(userName string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	for id, link := range ms.links {
+		if link.UserName == userName {
+			delete(ms.links, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package identities
+
+import "testing"
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
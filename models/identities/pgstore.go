@@ -0,0 +1,114 @@
+package identities
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+//pgLinkColumns are the columns of the identity_links table
+const pgLinkColumns = "id, provider, issuer, subject, user_name, email, linked"
+
+//PostgresStore is an implementation of the Store interface for PostgreSQL.
+//The identity_links table is created by the users.PostgresStore's
+//migrations, so that store must be constructed first.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+//NewPostgresStore constructs a new PostgresStore that uses db
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+//pgScanner is implemented by both sql.Row and sql.Rows
+type pgScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+//scanLink scans a row containing the pgLinkColumns
+func scanLink(row pgScanner) (*Link, error) {
+	link := &Link{}
+	err := row.Scan(&link.ID, &link.Provider, &link.Issuer, &link.Subject,
+		&link.UserName, &link.Email, &link.Linked)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+//Insert inserts a new link
+func (ps *PostgresStore) Insert(link *Link) error {
+	//ON CONFLICT DO NOTHING relies on the primary key to make
+	//the existence check and insert atomic
+	result, err := ps.db.Exec("INSERT INTO identity_links ("+pgLinkColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING",
+		link.ID, link.Provider, link.Issuer, link.Subject, link.UserName, link.Email, link.Linked)
+	if err != nil {
+		return fmt.Errorf("error inserting link: %v", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error inserting link: %v", err)
+	}
+	if inserted == 0 {
+		return ErrAlreadyLinked
+	}
+	return nil
+}
+
+//Get gets the link for the identity with the issuer and subject
+func (ps *PostgresStore) Get(issuer string, subject string) (*Link, error) {
+	row := ps.db.QueryRow("SELECT "+pgLinkColumns+" FROM identity_links WHERE id = $1", LinkID(issuer, subject))
+	link, err := scanLink(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting link: %v", err)
+	}
+	return link, nil
+}
+
+//List lists the user's links, oldest first
+func (ps *PostgresStore) List(userName string) ([]*Link, error) {
+	rows, err := ps.db.Query("SELECT "+pgLinkColumns+" FROM identity_links WHERE user_name = $1 ORDER BY linked", userName)
+	if err != nil {
+		return nil, fmt.Errorf("error listing links: %v", err)
+	}
+	defer rows.Close()
+	links := []*Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error listing links: %v", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing links: %v", err)
+	}
+	return links, nil
+}
+
+//Delete deletes the user's link with the given ID
+func (ps *PostgresStore) Delete(userName string, id string) error {
+	result, err := ps.db.Exec("DELETE FROM identity_links WHERE id = $1 AND user_name = $2", id, userName)
+	if err != nil {
+		return fmt.Errorf("error deleting link: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error deleting link: %v", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+//DeleteAll deletes all of the user's links
+func (ps *PostgresStore) DeleteAll(userName string) error {
+	if _, err := ps.db.Exec("DELETE FROM identity_links WHERE user_name = $1", userName); err != nil {
+		return fmt.Errorf("error deleting links: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package identities
+
+import "errors"
+
+//ErrNotFound is returned when the requested link doesn't exist
+var ErrNotFound = errors.New("linked identity not found")
+
+//ErrAlreadyLinked is returned from Store.Insert when
+//the identity is already linked to a user
+var ErrAlreadyLinked = errors.New("identity is already linked to an account")
+
+//Store describes what a linked identity store can do
+type Store interface {
+	//Insert inserts a new link, returning ErrAlreadyLinked
+	//if the identity is already linked
+	Insert(link *Link) error
+	//Get gets the link for the identity with the issuer and subject
+	Get(issuer string, subject string) (*Link, error)
+	//List lists the user's links, oldest first
+	List(userName string) ([]*Link, error)
+	//Delete deletes the user's link with the given ID
+	Delete(userName string, id string) error
+	//DeleteAll deletes all of the user's links
+	DeleteAll(userName string) error
+}
@@ -0,0 +1,64 @@
+package identities
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+//testStore runs the conformance tests that every Store implementation must pass
+func testStore(t *testing.T, store Store) {
+	suffix := time.Now().UnixNano()
+	userName := fmt.Sprintf("tester-%d", suffix)
+	subject := fmt.Sprintf("%d", suffix)
+
+	link := NewLink("google", "https://accounts.google.com", subject, userName, "test@example.com")
+	if err := store.Insert(link); err != nil {
+		t.Fatalf("error inserting link: %v", err)
+	}
+	other := NewLink("google", "https://accounts.google.com", subject, "other", "")
+	if err := store.Insert(other); err != ErrAlreadyLinked {
+		t.Errorf("incorrect error linking a linked identity: expected %v but got %v", ErrAlreadyLinked, err)
+	}
+
+	got, err := store.Get("https://accounts.google.com", subject)
+	if err != nil {
+		t.Fatalf("error getting link: %v", err)
+	}
+	if got.UserName != userName || got.ID != link.ID || got.Email != link.Email {
+		t.Errorf("incorrect link: %+v", got)
+	}
+	if _, err := store.Get("https://other.example.com", subject); err != ErrNotFound {
+		t.Errorf("incorrect error getting link for another issuer: expected %v but got %v", ErrNotFound, err)
+	}
+
+	second := NewLink("other", "https://other.example.com", subject, userName, "")
+	second.Linked = link.Linked.Add(time.Second)
+	store.Insert(second)
+	links, err := store.List(userName)
+	if err != nil {
+		t.Fatalf("error listing links: %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("incorrect number of links: expected 2 but got %d", len(links))
+	}
+	if links[0].ID != link.ID || links[1].ID != second.ID {
+		t.Errorf("links were not listed oldest first")
+	}
+
+	if err := store.Delete("other", link.ID); err != ErrNotFound {
+		t.Errorf("incorrect error deleting another user's link: expected %v but got %v", ErrNotFound, err)
+	}
+	if err := store.Delete(userName, link.ID); err != nil {
+		t.Fatalf("error deleting link: %v", err)
+	}
+	if err := store.Delete(userName, link.ID); err != ErrNotFound {
+		t.Errorf("incorrect error deleting a deleted link: expected %v but got %v", ErrNotFound, err)
+	}
+	if err := store.DeleteAll(userName); err != nil {
+		t.Fatalf("error deleting all links: %v", err)
+	}
+	if links, _ := store.List(userName); len(links) != 0 {
+		t.Errorf("links remain after deleting all: %d", len(links))
+	}
+}
@@ -0,0 +1,110 @@
+//Package ratelimit implements token-bucket rate limiting, with
+//per-route rules and pluggable stores for the buckets
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Limit allows Requests requests per Period. Buckets hold up to Requests
+//tokens, so a client may burst up to Requests at once, and refill at a
+//steady rate of Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+//Rule applies a Limit to requests matching a method and path
+type Rule struct {
+	//Method is the request method, or "*" for any method
+	Method string
+	//Path is the request path. If it ends with a "/", it matches any
+	//path beginning with it, so "/" matches all paths.
+	Path  string
+	Limit Limit
+}
+
+//ParseRule parses a rule in the form "<method> <path> <requests>/<period>",
+//for example "POST /users 10/1h"
+func ParseRule(s string) (*Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid rate limit rule '%s': expected '<method> <path> <requests>/<period>'", s)
+	}
+	parts := strings.SplitN(fields[2], "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid rate limit '%s': expected '<requests>/<period>'", fields[2])
+	}
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return nil, fmt.Errorf("invalid number of requests '%s' in rate limit rule", parts[0])
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("invalid period '%s' in rate limit rule", parts[1])
+	}
+	return &Rule{
+		Method: strings.ToUpper(fields[0]),
+		Path:   fields[1],
+		Limit:  Limit{Requests: requests, Period: period},
+	}, nil
+}
+
+//Matches returns true if the rule applies to r
+func (rule *Rule) Matches(r *http.Request) bool {
+	if rule.Method != "*" && rule.Method != r.Method {
+		return false
+	}
+	if strings.HasSuffix(rule.Path, "/") {
+		return strings.HasPrefix(r.URL.Path, rule.Path)
+	}
+	return r.URL.Path == rule.Path
+}
+
+//String returns the rule in the form accepted by ParseRule
+func (rule *Rule) String() string {
+	return fmt.Sprintf("%s %s %d/%s", rule.Method, rule.Path, rule.Limit.Requests, rule.Limit.Period)
+}
+
+//Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	//Limit is the bucket capacity
+	Limit int
+	//Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	//Reset is how long until the bucket is full again
+	Reset time.Duration
+	//RetryAfter is how long until a token is available,
+	//or zero if the request was allowed
+	RetryAfter time.Duration
+}
+
+//refill returns the tokens in a bucket that held tokens elapsed ago
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed > 0 {
+		tokens += float64(elapsed) / float64(limit.Period) * float64(limit.Requests)
+	}
+	return math.Min(tokens, float64(limit.Requests))
+}
+
+//newResult constructs the Result for a bucket holding
+//tokens after the request was allowed or rejected
+func newResult(allowed bool, tokens float64, limit Limit) *Result {
+	perToken := float64(limit.Period) / float64(limit.Requests)
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) * perToken),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return result
+}
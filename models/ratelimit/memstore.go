@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//bucket is a token bucket held in memory
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and single-instance deployments
+type MemStore struct {
+	mx      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		buckets: map[string]*bucket{},
+		swept:   time.Now(),
+	}
+}
+
+//Take refills the bucket for key and takes a token from it, if available
+func (ms *MemStore) Take(key string, limit Limit) (*Result, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	now := time.Now()
+	b, found := ms.buckets[key]
+	if !found {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		ms.buckets[key] = b
+	}
+	b.tokens = refill(b.tokens, now.Sub(b.updated), limit)
+	b.updated = now
+	b.period = limit.Period
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	ms.sweep(now)
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+//sweep periodically removes buckets that have had time to refill
+//completely, as they are equivalent to new buckets
+func (ms *MemStore) sweep(now time.Time) {
+	if now.Sub(ms.swept) < time.Minute {
+		return
+	}
+	for k, b := range ms.buckets {
+		if now.Sub(b.updated) > b.period {
+			delete(ms.buckets, k)
+		}
+	}
+	ms.swept = now
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("post /users/ 10/1h")
+	if err != nil {
+		t.Fatalf("error parsing rule: %v", err)
+	}
+	if rule.Method != "POST" || rule.Path != "/users/" || rule.Limit.Requests != 10 || rule.Limit.Period != time.Hour {
+		t.Errorf("incorrectly parsed rule: %+v", rule)
+	}
+	if !rule.Matches(httptest.NewRequest("POST", "/users/tester", nil)) {
+		t.Errorf("rule with trailing slash should match paths beneath it")
+	}
+	if rule.Matches(httptest.NewRequest("GET", "/users/tester", nil)) {
+		t.Errorf("rule should not match a different method")
+	}
+
+	exact, _ := ParseRule("* /users 10/1h")
+	if !exact.Matches(httptest.NewRequest("GET", "/users", nil)) || exact.Matches(httptest.NewRequest("GET", "/users/x", nil)) {
+		t.Errorf("rule without trailing slash should match only its exact path")
+	}
+
+	for _, invalid := range []string{"POST /users", "POST /users 10", "POST /users x/1h", "POST /users 10/x", "POST /users 0/1h"} {
+		if _, err := ParseRule(invalid); err == nil {
+			t.Errorf("expected error parsing '%s'", invalid)
+		}
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+	limit := Limit{Requests: 3, Period: time.Hour}
+	for i := 0; i < limit.Requests; i++ {
+		result, err := store.Take("client", limit)
+		if err != nil {
+			t.Fatalf("error taking token: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d should have been allowed", i)
+		}
+		if result.Remaining != limit.Requests-i-1 {
+			t.Errorf("incorrect remaining tokens: expected %d but got %d", limit.Requests-i-1, result.Remaining)
+		}
+	}
+	result, err := store.Take("client", limit)
+	if err != nil {
+		t.Fatalf("error taking token: %v", err)
+	}
+	if result.Allowed {
+		t.Errorf("request beyond the limit should have been rejected")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > limit.Period/time.Duration(limit.Requests) {
+		t.Errorf("incorrect RetryAfter: %v", result.RetryAfter)
+	}
+	if result, _ := store.Take("other", limit); !result.Allowed {
+		t.Errorf("buckets for different keys should be independent")
+	}
+
+	fast := Limit{Requests: 1, Period: 10 * time.Millisecond}
+	store.Take("fast", fast)
+	time.Sleep(20 * time.Millisecond)
+	if result, _ := store.Take("fast", fast); !result.Allowed {
+		t.Errorf("bucket was not refilled")
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to keys to form redis keys
+const redisKeyPrefix = "ratelimit:"
+
+//takeScript refills and takes a token from the bucket stored in the hash at
+//KEYS[1]. ARGV holds the capacity, the period in milliseconds, and the current
+//time in milliseconds. It returns whether a token was taken and the tokens
+//remaining, as a string so that fractional tokens are preserved. Buckets
+//expire once they would have refilled completely.
+var takeScript = redis.NewScript(1, `
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil then
+	tokens = capacity
+	updated = now
+end
+if now > updated then
+	tokens = math.min(capacity, tokens + (now - updated) / period * capacity)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, tostring(tokens)}
+`)
+
+//RedisStore is an implementation of the Store interface for redis,
+//which shares buckets across all instances of the service
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Take refills the bucket for key and takes a token from it, if available
+func (rs *RedisStore) Take(key string, limit Limit) (*Result, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	reply, err := redis.Values(takeScript.Do(conn, redisKeyPrefix+key, limit.Requests,
+		limit.Period.Nanoseconds()/int64(time.Millisecond),
+		time.Now().UnixNano()/int64(time.Millisecond)))
+	if err != nil {
+		return nil, fmt.Errorf("error taking token: %v", err)
+	}
+	allowed, err := redis.Int(reply[0], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding token bucket: %v", err)
+	}
+	s, err := redis.String(reply[1], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding token bucket: %v", err)
+	}
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding token bucket: %v", err)
+	}
+	return newResult(allowed == 1, tokens, limit), nil
+}
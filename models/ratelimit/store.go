@@ -0,0 +1,8 @@
+package ratelimit
+
+//Store describes what a token bucket store can do
+type Store interface {
+	//Take atomically refills the bucket for key according to limit
+	//and then takes a token from it, if one is available
+	Take(key string, limit Limit) (*Result, error)
+}
@@ -0,0 +1,108 @@
+package refreshtokens
+
+import (
+	"sync"
+	"time"
+)
+
+//memToken is a token stored in a MemStore
+type memToken struct {
+	token *Token
+	used  bool
+}
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx       sync.Mutex
+	tokens   map[string]*memToken
+	families map[string][]string
+	swept    time.Time
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		tokens:   map[string]*memToken{},
+		families: map[string][]string{},
+		swept:    time.Now(),
+	}
+}
+
+//Insert inserts a new token
+func (ms *MemStore) Insert(token *Token) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	ms.sweep(time.Now())
+	stored := *token
+	ms.tokens[token.Hash] = &memToken{token: &stored}
+	ms.families[token.Family] = append(ms.families[token.Family], token.Hash)
+	return nil
+}
+
+//Take marks the token with the given hash as used and returns it
+func (ms *MemStore) Take(hash string) (*Token, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	mt, found := ms.tokens[hash]
+	if !found || mt.token.Expired() {
+		return nil, ErrNotFound
+	}
+	token := *mt.token
+	if mt.used {
+		return &token, ErrReused
+	}
+	mt.used = true
+	return &token, nil
+}
+
+//RevokeFamily deletes all tokens in the family
+func (ms *MemStore) RevokeFamily(family string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	for _, hash := range ms.families[family] {
+		delete(ms.tokens, hash)
+	}
+	delete(ms.families, family)
+	return nil
+}
+
+//RevokeAll deletes all of the user's tokens, in every family
+func (ms *MemStore) RevokeAll(userName string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	for family, hashes := range ms.families {
+		if mt, found := ms.tokens[hashes[0]]; !found || mt.token.UserName != userName {
+			continue
+		}
+		for _, hash := range hashes {
+			delete(ms.tokens, hash)
+		}
+		delete(ms.families, family)
+	}
+	return nil
+}
+
+//sweep periodically removes expired tokens, along with whether they
+//were used, so that the maps don't grow without bound
+func (ms *MemStore) sweep(now time.Time) {
+	if now.Sub(ms.swept) < time.Minute {
+		return
+	}
+	for family, hashes := range ms.families {
+		remaining := hashes[:0]
+		for _, hash := range hashes {
+			if mt, found := ms.tokens[hash]; found && now.After(mt.token.Expires) {
+				delete(ms.tokens, hash)
+				continue
+			}
+			remaining = append(remaining, hash)
+		}
+		if len(remaining) == 0 {
+			delete(ms.families, family)
+			continue
+		}
+		ms.families[family] = remaining
+	}
+	ms.swept = now
+}
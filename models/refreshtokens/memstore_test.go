@@ -0,0 +1,105 @@
+package refreshtokens
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+	authTime := time.Now()
+
+	plaintext, token, err := NewToken("tester", "", authTime, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+	if token.Hash == plaintext {
+		t.Fatalf("token hash must not equal the plaintext token")
+	}
+	if token.Family != token.Hash {
+		t.Errorf("first token in a family must start the family")
+	}
+	if err := store.Insert(token); err != nil {
+		t.Fatalf("error inserting token: %v", err)
+	}
+
+	got, err := store.Take(HashToken(plaintext))
+	if err != nil {
+		t.Fatalf("error taking token: %v", err)
+	}
+	if got.UserName != "tester" {
+		t.Errorf("incorrect userName: expected tester but got %s", got.UserName)
+	}
+
+	_, rotated, _ := NewToken("tester", got.Family, got.AuthTime, time.Hour, 0)
+	if rotated.Family != token.Family {
+		t.Errorf("rotated token must keep the family")
+	}
+	store.Insert(rotated)
+
+	reused, err := store.Take(token.Hash)
+	if err != ErrReused {
+		t.Fatalf("incorrect error taking a used token: expected %v but got %v", ErrReused, err)
+	}
+	if err := store.RevokeFamily(reused.Family); err != nil {
+		t.Fatalf("error revoking family: %v", err)
+	}
+	if _, err := store.Take(rotated.Hash); err != ErrNotFound {
+		t.Errorf("incorrect error taking a revoked token: expected %v but got %v", ErrNotFound, err)
+	}
+
+	_, expired, _ := NewToken("tester", "", authTime, -time.Minute, 0)
+	store.Insert(expired)
+	if _, err := store.Take(expired.Hash); err != ErrNotFound {
+		t.Errorf("incorrect error taking an expired token: expected %v but got %v", ErrNotFound, err)
+	}
+
+	//expired tokens and their families are removed by the periodic sweep
+	store.swept = time.Now().Add(-2 * time.Minute)
+	_, fresh, _ := NewToken("tester", "", authTime, time.Hour, 0)
+	store.Insert(fresh)
+	if _, found := store.tokens[expired.Hash]; found {
+		t.Errorf("expired token should have been swept")
+	}
+	if _, found := store.families[expired.Family]; found {
+		t.Errorf("family with only expired tokens should have been swept")
+	}
+	if _, found := store.tokens[fresh.Hash]; !found {
+		t.Errorf("unexpired token should not have been swept")
+	}
+}
+
+func TestNewTokenMaxAge(t *testing.T) {
+	authTime := time.Now().Add(-time.Hour)
+	_, token, err := NewToken("tester", "", authTime, 24*time.Hour, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+	if limit := authTime.Add(2 * time.Hour); !token.Expires.Equal(limit) {
+		t.Errorf("incorrect expiry: expected the family limit %v but got %v", limit, token.Expires)
+	}
+	if _, _, err := NewToken("tester", token.Family, authTime, 24*time.Hour, time.Hour); err != ErrFamilyExpired {
+		t.Errorf("incorrect error rotating a token past the family limit: expected %v but got %v", ErrFamilyExpired, err)
+	}
+}
+
+func TestMemStoreRevokeAll(t *testing.T) {
+	store := NewMemStore()
+	_, first, _ := NewToken("tester", "", time.Now(), time.Hour, 0)
+	_, second, _ := NewToken("tester", "", time.Now(), time.Hour, 0)
+	_, other, _ := NewToken("other", "", time.Now(), time.Hour, 0)
+	for _, token := range []*Token{first, second, other} {
+		store.Insert(token)
+	}
+	if err := store.RevokeAll("tester"); err != nil {
+		t.Fatalf("error revoking all tokens: %v", err)
+	}
+	for _, token := range []*Token{first, second} {
+		if _, err := store.Take(token.Hash); err != ErrNotFound {
+			t.Errorf("incorrect error taking a revoked token: expected %v but got %v", ErrNotFound, err)
+		}
+	}
+	if _, err := store.Take(other.Hash); err != nil {
+		t.Errorf("another user's token was revoked: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package refreshtokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	//redisKeyPrefix is prepended to token hashes to form redis keys
+	redisKeyPrefix = "refresh:"
+	//redisUsedKeyPrefix is prepended to token hashes to form
+	//the keys that mark tokens as used
+	redisUsedKeyPrefix = "refresh-used:"
+	//redisFamilyKeyPrefix is prepended to family IDs to form the keys
+	//of the sets containing the hashes of the tokens in each family
+	redisFamilyKeyPrefix = "refresh-family:"
+	//redisUserKeyPrefix is prepended to user names to form the keys
+	//of the sets containing the IDs of each user's families
+	redisUserKeyPrefix = "refresh-user:"
+)
+
+//RedisStore is an implementation of the Store interface for redis.
+//Tokens are stored with a redis TTL so they are removed once they expire.
+//Used tokens are kept until they expire so that reuse can be detected.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Insert inserts a new token
+func (rs *RedisStore) Insert(token *Token) error {
+	ttl := time.Until(token.Expires)
+	if ttl <= 0 {
+		return fmt.Errorf("token has already expired")
+	}
+	j, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("error encoding token: %v", err)
+	}
+	ms := ttl.Nanoseconds() / int64(time.Millisecond)
+	familyKey := redisFamilyKeyPrefix + token.Family
+	userKey := redisUserKeyPrefix + token.UserName
+	conn := rs.pool.Get()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("SET", redisKeyPrefix+token.Hash, j, "PX", ms)
+	conn.Send("SADD", familyKey, token.Hash)
+	//each new token in a family expires after the previous ones
+	conn.Send("PEXPIRE", familyKey, ms)
+	conn.Send("SADD", userKey, token.Family)
+	//keep the user's set until the last of their tokens expires, which
+	//may be in another family; NX and GT require redis 7 or later
+	conn.Send("PEXPIRE", userKey, ms, "NX")
+	conn.Send("PEXPIRE", userKey, ms, "GT")
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("error inserting token: %v", err)
+	}
+	return nil
+}
+
+//Take marks the token with the given hash as used and returns it
+func (rs *RedisStore) Take(hash string) (*Token, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	key := redisKeyPrefix + hash
+	conn.Send("MULTI")
+	conn.Send("GET", key)
+	conn.Send("PTTL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %v", err)
+	}
+	j, err := redis.Bytes(replies[0], nil)
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %v", err)
+	}
+	token := &Token{}
+	if err := json.Unmarshal(j, token); err != nil {
+		return nil, fmt.Errorf("error decoding token: %v", err)
+	}
+	if token.Expired() {
+		return nil, ErrNotFound
+	}
+
+	//SET NX succeeds only for the first request to use the
+	//token, so concurrent requests can't both use it
+	ms, _ := redis.Int64(replies[1], nil)
+	if ms <= 0 {
+		ms = 1
+	}
+	_, err = redis.String(conn.Do("SET", redisUsedKeyPrefix+hash, 1, "NX", "PX", ms))
+	if err == redis.ErrNil {
+		return token, ErrReused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error marking token as used: %v", err)
+	}
+	return token, nil
+}
+
+//RevokeAll deletes all of the user's tokens, in every family
+func (rs *RedisStore) RevokeAll(userName string) error {
+	conn := rs.pool.Get()
+	families, err := redis.Strings(conn.Do("SMEMBERS", redisUserKeyPrefix+userName))
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("error getting token families: %v", err)
+	}
+	for _, family := range families {
+		if err := rs.RevokeFamily(family); err != nil {
+			return err
+		}
+	}
+	conn = rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", redisUserKeyPrefix+userName); err != nil {
+		return fmt.Errorf("error revoking token families: %v", err)
+	}
+	return nil
+}
+
+//RevokeFamily deletes all tokens in the family
+func (rs *RedisStore) RevokeFamily(family string) error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	familyKey := redisFamilyKeyPrefix + family
+	hashes, err := redis.Strings(conn.Do("SMEMBERS", familyKey))
+	if err != nil {
+		return fmt.Errorf("error getting token family: %v", err)
+	}
+	keys := []interface{}{familyKey}
+	for _, hash := range hashes {
+		keys = append(keys, redisKeyPrefix+hash, redisUsedKeyPrefix+hash)
+	}
+	if _, err := conn.Do("DEL", keys...); err != nil {
+		return fmt.Errorf("error revoking token family: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package refreshtokens
+
+import "errors"
+
+//ErrNotFound is returned from Store.Take when the token doesn't exist,
+//has expired, or its family has been revoked
+var ErrNotFound = errors.New("refresh token not found or expired")
+
+//ErrReused is returned from Store.Take when the token has already been used
+var ErrReused = errors.New("refresh token has already been used")
+
+//Store describes what a refresh token store can do
+type Store interface {
+	//Insert inserts a new token
+	Insert(token *Token) error
+	//Take atomically marks the token with the given hash as used and
+	//returns it. If the token was already used, it returns the token
+	//along with ErrReused, so that the caller can revoke its family.
+	Take(hash string) (*Token, error)
+	//RevokeFamily deletes all tokens in the family
+	RevokeFamily(family string) error
+	//RevokeAll deletes all of the user's tokens, in every family
+	RevokeAll(userName string) error
+}
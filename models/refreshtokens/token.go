@@ -0,0 +1,79 @@
+package refreshtokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//tokenLength is the number of random bytes in a refresh token
+const tokenLength = 32
+
+//ErrFamilyExpired is returned from NewToken when the family
+//has reached its maximum age and can't be rotated any further
+var ErrFamilyExpired = errors.New("refresh token family has expired; please sign in again")
+
+//Token represents an opaque refresh token. Each token may be used only
+//once, in exchange for a new token in the same family; using a token
+//a second time indicates it was stolen, so the whole family is revoked.
+//Only the hash of the token is stored.
+type Token struct {
+	Hash string `json:"hash"`
+	//Family is the hash of the first token issued at sign-in,
+	//which is shared by all tokens rotated from it
+	Family   string `json:"family"`
+	UserName string `json:"userName"`
+	//AuthTime is when the user signed in to obtain the first token
+	AuthTime time.Time `json:"authTime"`
+	Expires  time.Time `json:"expires"`
+}
+
+//NewToken generates a new refresh token for userName that expires after ttl,
+//but no later than maxAge after authTime, so that a family can't be rotated
+//forever; pass a zero maxAge for no limit. Pass an empty family to start a
+//new family, or the family of the token being rotated. It returns the
+//plaintext token, which should be sent to the client, and the Token,
+//which should be inserted into a Store.
+func NewToken(userName string, family string, authTime time.Time, ttl time.Duration, maxAge time.Duration) (string, *Token, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+	if maxAge > 0 {
+		if limit := authTime.Add(maxAge); limit.Before(expires) {
+			expires = limit
+		}
+		if !expires.After(now) {
+			return "", nil, ErrFamilyExpired
+		}
+	}
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("error generating random token: %v", err)
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+	hash := HashToken(plaintext)
+	if len(family) == 0 {
+		family = hash
+	}
+	return plaintext, &Token{
+		Hash:     hash,
+		Family:   family,
+		UserName: userName,
+		AuthTime: authTime,
+		Expires:  expires,
+	}, nil
+}
+
+//HashToken returns the hash of a plaintext token
+func HashToken(plaintext string) string {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Expired returns true if the token has expired
+func (t *Token) Expired() bool {
+	return time.Now().After(t.Expires)
+}
@@ -0,0 +1,41 @@
+package resets
+
+import "sync"
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx     sync.Mutex
+	tokens map[string]*Token
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		tokens: map[string]*Token{},
+	}
+}
+
+//Insert inserts a new token
+func (ms *MemStore) Insert(token *Token) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	stored := *token
+	ms.tokens[token.Hash] = &stored
+	return nil
+}
+
+//Take gets and deletes the token with the given hash
+func (ms *MemStore) Take(hash string) (*Token, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	token, found := ms.tokens[hash]
+	if !found {
+		return nil, ErrNotFound
+	}
+	delete(ms.tokens, hash)
+	if token.Expired() {
+		return nil, ErrNotFound
+	}
+	return token, nil
+}
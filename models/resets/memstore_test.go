@@ -0,0 +1,41 @@
+package resets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+
+	plaintext, token, err := NewToken("tester", time.Hour)
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+	if token.Hash == plaintext {
+		t.Fatalf("token hash must not equal the plaintext token")
+	}
+	if err := store.Insert(token); err != nil {
+		t.Fatalf("error inserting token: %v", err)
+	}
+
+	got, err := store.Take(HashToken(plaintext))
+	if err != nil {
+		t.Fatalf("error taking token: %v", err)
+	}
+	if got.UserName != "tester" {
+		t.Errorf("incorrect userName: expected tester but got %s", got.UserName)
+	}
+	if _, err := store.Take(HashToken(plaintext)); err != ErrNotFound {
+		t.Errorf("incorrect error taking a used token: expected %v but got %v", ErrNotFound, err)
+	}
+
+	_, expired, err := NewToken("tester", -time.Minute)
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+	store.Insert(expired)
+	if _, err := store.Take(expired.Hash); err != ErrNotFound {
+		t.Errorf("incorrect error taking an expired token: expected %v but got %v", ErrNotFound, err)
+	}
+}
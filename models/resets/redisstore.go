@@ -0,0 +1,75 @@
+package resets
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to token hashes to form redis keys
+const redisKeyPrefix = "reset:"
+
+//RedisStore is an implementation of the Store interface for redis.
+//Tokens are stored with a redis TTL so they are removed once they expire.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Insert inserts a new token
+func (rs *RedisStore) Insert(token *Token) error {
+	ttl := time.Until(token.Expires)
+	if ttl <= 0 {
+		return fmt.Errorf("token has already expired")
+	}
+	j, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("error encoding token: %v", err)
+	}
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", redisKeyPrefix+token.Hash, j, "PX", ttl.Nanoseconds()/int64(time.Millisecond)); err != nil {
+		return fmt.Errorf("error inserting token: %v", err)
+	}
+	return nil
+}
+
+//Take gets and deletes the token with the given hash
+func (rs *RedisStore) Take(hash string) (*Token, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	//GET and DEL within a transaction so that
+	//concurrent requests can't both use the token
+	key := redisKeyPrefix + hash
+	conn.Send("MULTI")
+	conn.Send("GET", key)
+	conn.Send("DEL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %v", err)
+	}
+	j, err := redis.Bytes(replies[0], nil)
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %v", err)
+	}
+	token := &Token{}
+	if err := json.Unmarshal(j, token); err != nil {
+		return nil, fmt.Errorf("error decoding token: %v", err)
+	}
+	if token.Expired() {
+		return nil, ErrNotFound
+	}
+	return token, nil
+}
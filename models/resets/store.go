@@ -0,0 +1,16 @@
+package resets
+
+import "errors"
+
+//ErrNotFound is returned from Store.Take when the token doesn't exist,
+//has already been used, or has expired
+var ErrNotFound = errors.New("reset token not found or expired")
+
+//Store describes what a reset token store can do
+type Store interface {
+	//Insert inserts a new token
+	Insert(token *Token) error
+	//Take atomically gets and deletes the token with the given hash,
+	//so that each token can be used only once
+	Take(hash string) (*Token, error)
+}
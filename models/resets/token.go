@@ -0,0 +1,77 @@
+package resets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+//tokenLength is the number of random bytes in a reset token
+const tokenLength = 32
+
+//Token represents a single-use password reset token.
+//Only the hash of the token is stored, so that someone
+//with read access to the store can't reset passwords.
+type Token struct {
+	Hash     string    `json:"hash"`
+	UserName string    `json:"userName"`
+	Expires  time.Time `json:"expires"`
+}
+
+//NewToken generates a new reset token for userName that expires after ttl.
+//It returns the plaintext token, which should be sent to the user,
+//and the Token, which should be inserted into a Store.
+func NewToken(userName string, ttl time.Duration) (string, *Token, error) {
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("error generating random token: %v", err)
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+	return plaintext, &Token{
+		Hash:     HashToken(plaintext),
+		UserName: userName,
+		Expires:  time.Now().Add(ttl),
+	}, nil
+}
+
+//HashToken returns the hash of a plaintext token
+func HashToken(plaintext string) string {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Expired returns true if the token has expired
+func (t *Token) Expired() bool {
+	return time.Now().After(t.Expires)
+}
+
+//Request represents a request to reset a password,
+//identifying the account by either userName or email
+type Request struct {
+	UserName string `json:"userName,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+//Validate validates the Request
+func (r *Request) Validate() error {
+	if len(r.UserName) == 0 && len(r.Email) == 0 {
+		return fmt.Errorf("userName or email must be supplied")
+	}
+	return nil
+}
+
+//Reset represents the new password sent along with a reset token
+type Reset struct {
+	Password string `json:"password"`
+}
+
+//Validate validates the Reset
+func (r *Reset) Validate() error {
+	if len(r.Password) == 0 {
+		return fmt.Errorf("password must be supplied")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+//Package smscodes manages the one-time codes sent via SMS
+//to verify that a user controls a mobile number
+package smscodes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+//codeDigits is the number of digits in a code
+const codeDigits = 6
+
+//MaxAttempts is the number of incorrect guesses allowed before a code is discarded
+const MaxAttempts = 5
+
+//Code is a pending one-time code sent to a user's mobile number.
+//Only the hash of the code is stored.
+type Code struct {
+	UserName string    `json:"userName"`
+	Mobile   string    `json:"mobile"`
+	Hash     string    `json:"hash"`
+	Expires  time.Time `json:"expires"`
+	Attempts int       `json:"attempts"`
+}
+
+//NewCode generates a new random code for userName's mobile number that
+//expires after ttl. It returns the plaintext code, which should be sent to
+//the mobile number, and the Code, which should be saved to a Store.
+func NewCode(userName string, mobile string, ttl time.Duration) (string, *Code, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating random code: %v", err)
+	}
+	plaintext := fmt.Sprintf("%0*d", codeDigits, n)
+	return plaintext, &Code{
+		UserName: userName,
+		Mobile:   mobile,
+		Hash:     hashCode(userName, plaintext),
+		Expires:  time.Now().Add(ttl),
+	}, nil
+}
+
+//Matches returns true if plaintext matches the code
+func (c *Code) Matches(plaintext string) bool {
+	return subtle.ConstantTimeCompare([]byte(c.Hash), []byte(hashCode(c.UserName, plaintext))) == 1
+}
+
+//Exhausted returns true if no more incorrect attempts may be made against the code
+func (c *Code) Exhausted() bool {
+	return c.Attempts >= MaxAttempts
+}
+
+//Expired returns true if the code has expired
+func (c *Code) Expired() bool {
+	return time.Now().After(c.Expires)
+}
+
+//hashCode returns the hash of a plaintext code for userName
+func hashCode(userName string, plaintext string) string {
+	hash := sha256.Sum256([]byte(userName + "\x00" + plaintext))
+	return hex.EncodeToString(hash[:])
+}
+
+//Confirmation represents the code sent by the user to confirm their mobile number
+type Confirmation struct {
+	Code string `json:"code"`
+}
+
+//Validate validates the Confirmation
+func (c *Confirmation) Validate() error {
+	if len(c.Code) == 0 {
+		return fmt.Errorf("code must be supplied")
+	}
+	return nil
+}
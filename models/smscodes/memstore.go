@@ -0,0 +1,58 @@
+package smscodes
+
+import "sync"
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx    sync.Mutex
+	codes map[string]*Code
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		codes: map[string]*Code{},
+	}
+}
+
+//Save saves the code
+func (ms *MemStore) Save(code *Code) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	stored := *code
+	ms.codes[code.UserName] = &stored
+	return nil
+}
+
+//Get returns the pending code for userName
+func (ms *MemStore) Get(userName string) (*Code, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	code, found := ms.codes[userName]
+	if !found || code.Expired() {
+		return nil, ErrNotFound
+	}
+	c := *code
+	return &c, nil
+}
+
+//IncrementAttempts increments the number of incorrect attempts
+func (ms *MemStore) IncrementAttempts(userName string) (int, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	code, found := ms.codes[userName]
+	if !found {
+		return 0, ErrNotFound
+	}
+	code.Attempts++
+	return code.Attempts, nil
+}
+
+//Delete deletes the pending code for userName
+func (ms *MemStore) Delete(userName string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	delete(ms.codes, userName)
+	return nil
+}
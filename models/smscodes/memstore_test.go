@@ -0,0 +1,65 @@
+package smscodes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+
+	plaintext, code, err := NewCode("tester", "+12065551212", time.Minute)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+	if len(plaintext) != codeDigits {
+		t.Errorf("incorrect code length: expected %d but got %d", codeDigits, len(plaintext))
+	}
+	if err := store.Save(code); err != nil {
+		t.Fatalf("error saving code: %v", err)
+	}
+
+	got, err := store.Get("tester")
+	if err != nil {
+		t.Fatalf("error getting code: %v", err)
+	}
+	if !got.Matches(plaintext) {
+		t.Errorf("code does not match its plaintext")
+	}
+	if got.Matches("not-the-code") {
+		t.Errorf("code matches incorrect plaintext")
+	}
+
+	for i := 1; i <= 2; i++ {
+		attempts, err := store.IncrementAttempts("tester")
+		if err != nil {
+			t.Fatalf("error incrementing attempts: %v", err)
+		}
+		if attempts != i {
+			t.Errorf("incorrect attempts: expected %d but got %d", i, attempts)
+		}
+	}
+
+	//attempts carried over to a re-sent code are saved with it
+	_, resent, err := NewCode("tester", "+12065551212", time.Minute)
+	if err != nil {
+		t.Fatalf("error generating re-sent code: %v", err)
+	}
+	resent.Attempts = MaxAttempts
+	if err := store.Save(resent); err != nil {
+		t.Fatalf("error saving re-sent code: %v", err)
+	}
+	if got, err := store.Get("tester"); err != nil || !got.Exhausted() {
+		t.Errorf("re-sent code should keep its attempts and be exhausted: %+v, %v", got, err)
+	}
+
+	if err := store.Delete("tester"); err != nil {
+		t.Fatalf("error deleting code: %v", err)
+	}
+	if _, err := store.Get("tester"); err != ErrNotFound {
+		t.Errorf("incorrect error getting deleted code: expected %v but got %v", ErrNotFound, err)
+	}
+	if _, err := store.IncrementAttempts("tester"); err != ErrNotFound {
+		t.Errorf("incorrect error incrementing deleted code: expected %v but got %v", ErrNotFound, err)
+	}
+}
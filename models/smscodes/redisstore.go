@@ -0,0 +1,107 @@
+package smscodes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to user names to form redis keys
+const redisKeyPrefix = "smscode:"
+
+//incrementScript increments the attempts field only if the code still exists,
+//so that HINCRBY never creates a new hash without a TTL
+var incrementScript = redis.NewScript(1, `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+end
+return -1
+`)
+
+//RedisStore is an implementation of the Store interface for redis.
+//Each code is stored as a redis hash with a TTL matching its expiration.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Save saves the code
+func (rs *RedisStore) Save(code *Code) error {
+	ttl := time.Until(code.Expires)
+	if ttl <= 0 {
+		return fmt.Errorf("code has already expired")
+	}
+	key := redisKeyPrefix + code.UserName
+	conn := rs.pool.Get()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", key)
+	conn.Send("HSET", key, "mobile", code.Mobile, "hash", code.Hash,
+		"expires", code.Expires.UnixNano(), "attempts", code.Attempts)
+	conn.Send("PEXPIRE", key, ttl.Nanoseconds()/int64(time.Millisecond))
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("error saving code: %v", err)
+	}
+	return nil
+}
+
+//Get returns the pending code for userName
+func (rs *RedisStore) Get(userName string) (*Code, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	vals, err := redis.StringMap(conn.Do("HGETALL", redisKeyPrefix+userName))
+	if err != nil {
+		return nil, fmt.Errorf("error getting code: %v", err)
+	}
+	if len(vals) == 0 {
+		return nil, ErrNotFound
+	}
+	code := &Code{
+		UserName: userName,
+		Mobile:   vals["mobile"],
+		Hash:     vals["hash"],
+	}
+	var expires int64
+	if _, err := fmt.Sscan(vals["expires"], &expires); err != nil {
+		return nil, fmt.Errorf("error decoding code expiration: %v", err)
+	}
+	code.Expires = time.Unix(0, expires)
+	if _, err := fmt.Sscan(vals["attempts"], &code.Attempts); err != nil {
+		return nil, fmt.Errorf("error decoding code attempts: %v", err)
+	}
+	if code.Expired() {
+		return nil, ErrNotFound
+	}
+	return code, nil
+}
+
+//IncrementAttempts increments the number of incorrect attempts
+func (rs *RedisStore) IncrementAttempts(userName string) (int, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	attempts, err := redis.Int(incrementScript.Do(conn, redisKeyPrefix+userName))
+	if err != nil {
+		return 0, fmt.Errorf("error incrementing attempts: %v", err)
+	}
+	if attempts < 0 {
+		return 0, ErrNotFound
+	}
+	return attempts, nil
+}
+
+//Delete deletes the pending code for userName
+func (rs *RedisStore) Delete(userName string) error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", redisKeyPrefix+userName); err != nil {
+		return fmt.Errorf("error deleting code: %v", err)
+	}
+	return nil
+}
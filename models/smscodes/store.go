@@ -0,0 +1,20 @@
+package smscodes
+
+import "errors"
+
+//ErrNotFound is returned when there is no pending code for the user,
+//or the code has expired
+var ErrNotFound = errors.New("no pending code; please request a new one")
+
+//Store describes what a code store can do. Each user has at most one pending code.
+type Store interface {
+	//Save saves the code, replacing any pending code for the same user
+	Save(code *Code) error
+	//Get returns the pending code for userName
+	Get(userName string) (*Code, error)
+	//IncrementAttempts increments and returns the number of
+	//incorrect attempts made against the pending code for userName
+	IncrementAttempts(userName string) (int, error)
+	//Delete deletes the pending code for userName
+	Delete(userName string) error
+}
@@ -0,0 +1,80 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+//memRecord is a Record with its expiration
+type memRecord struct {
+	Record
+	expires time.Time
+}
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and single-instance deployments
+type MemStore struct {
+	mx      sync.Mutex
+	records map[string]*memRecord
+	swept   time.Time
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		records: map[string]*memRecord{},
+		swept:   time.Now(),
+	}
+}
+
+//Get returns the record for key, or a zero Record if there is none
+func (ms *MemStore) Get(key string) (*Record, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	record, found := ms.records[key]
+	if !found || time.Now().After(record.expires) {
+		return &Record{}, nil
+	}
+	stored := record.Record
+	return &stored, nil
+}
+
+//Fail increments the failures for key and sets its last failure to now
+func (ms *MemStore) Fail(key string, ttl time.Duration) (*Record, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	now := time.Now()
+	record, found := ms.records[key]
+	if !found || now.After(record.expires) {
+		record = &memRecord{}
+		ms.records[key] = record
+	}
+	record.Failures++
+	record.LastFailure = now
+	record.expires = now.Add(ttl)
+	ms.sweep(now)
+	stored := record.Record
+	return &stored, nil
+}
+
+//Reset deletes the record for key
+func (ms *MemStore) Reset(key string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	delete(ms.records, key)
+	return nil
+}
+
+//sweep periodically removes expired records,
+//so that the map doesn't grow without bound
+func (ms *MemStore) sweep(now time.Time) {
+	if now.Sub(ms.swept) < time.Minute {
+		return
+	}
+	for k, r := range ms.records {
+		if now.After(r.expires) {
+			delete(ms.records, k)
+		}
+	}
+	ms.swept = now
+}
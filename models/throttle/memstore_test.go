@@ -0,0 +1,80 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := &Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	cases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{1000, 10 * time.Second},
+	}
+	for _, c := range cases {
+		if got := policy.delay(c.failures); got != c.expected {
+			t.Errorf("incorrect delay after %d failures: expected %v but got %v", c.failures, c.expected, got)
+		}
+	}
+}
+
+func TestMemStoreThrottler(t *testing.T) {
+	store := NewMemStore()
+	throttler := NewThrottler(store, "user:", Policy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	})
+	other := NewThrottler(store, "ip:", Policy{FreeAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		if retryAfter, err := throttler.Fail("tester"); err != nil || retryAfter != 0 {
+			t.Fatalf("free attempt %d was locked out: %v, %v", i, retryAfter, err)
+		}
+	}
+	retryAfter, err := throttler.Fail("tester")
+	if err != nil {
+		t.Fatalf("error recording failure: %v", err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("incorrect lockout after exceeding free attempts: %v", retryAfter)
+	}
+	if retryAfter, err := throttler.Check("tester"); err != nil || retryAfter <= 0 {
+		t.Errorf("check did not report lockout: %v, %v", retryAfter, err)
+	}
+	if retryAfter, err := other.Check("tester"); err != nil || retryAfter != 0 {
+		t.Errorf("throttlers with different prefixes should not share records: %v, %v", retryAfter, err)
+	}
+
+	if err := throttler.Reset("tester"); err != nil {
+		t.Fatalf("error resetting: %v", err)
+	}
+	if retryAfter, err := throttler.Check("tester"); err != nil || retryAfter != 0 {
+		t.Errorf("lockout remained after reset: %v, %v", retryAfter, err)
+	}
+
+	expiring := NewThrottler(store, "exp:", Policy{FreeAttempts: 0, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: -time.Second})
+	expiring.Fail("tester")
+	if retryAfter, err := expiring.Check("tester"); err != nil || retryAfter != 0 {
+		t.Errorf("expired failures should not cause a lockout: %v, %v", retryAfter, err)
+	}
+
+	//expired records are swept at most once a minute
+	if _, found := store.records["exp:tester"]; !found {
+		t.Errorf("expired record should remain until the next sweep")
+	}
+	store.swept = time.Now().Add(-2 * time.Minute)
+	throttler.Fail("tester")
+	if _, found := store.records["exp:tester"]; found {
+		t.Errorf("expired record should have been swept")
+	}
+}
@@ -0,0 +1,77 @@
+package throttle
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to keys to form redis keys
+const redisKeyPrefix = "throttle:"
+
+//RedisStore is an implementation of the Store interface for redis,
+//which shares failure records across all instances of the service.
+//Each record is stored as a redis hash with a TTL.
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection pool
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+//Get returns the record for key, or a zero Record if there is none
+func (rs *RedisStore) Get(key string) (*Record, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	vals, err := redis.Int64s(conn.Do("HMGET", redisKeyPrefix+key, "failures", "last"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting record: %v", err)
+	}
+	return newRecord(vals), nil
+}
+
+//Fail increments the failures for key and sets its last failure to now
+func (rs *RedisStore) Fail(key string, ttl time.Duration) (*Record, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	rkey := redisKeyPrefix + key
+	conn.Send("MULTI")
+	conn.Send("HINCRBY", rkey, "failures", 1)
+	conn.Send("HSET", rkey, "last", time.Now().UnixNano())
+	conn.Send("PEXPIRE", rkey, ttl.Nanoseconds()/int64(time.Millisecond))
+	conn.Send("HMGET", rkey, "failures", "last")
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("error recording failure: %v", err)
+	}
+	vals, err := redis.Int64s(replies[3], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error recording failure: %v", err)
+	}
+	return newRecord(vals), nil
+}
+
+//Reset deletes the record for key
+func (rs *RedisStore) Reset(key string) error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", redisKeyPrefix+key); err != nil {
+		return fmt.Errorf("error resetting record: %v", err)
+	}
+	return nil
+}
+
+//newRecord constructs a Record from the failures and last
+//failure fields of a redis hash; missing fields are zero
+func newRecord(vals []int64) *Record {
+	record := &Record{Failures: int(vals[0])}
+	if vals[1] > 0 {
+		record.LastFailure = time.Unix(0, vals[1])
+	}
+	return record
+}
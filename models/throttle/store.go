@@ -0,0 +1,14 @@
+package throttle
+
+import "time"
+
+//Store describes what a failure record store can do
+type Store interface {
+	//Get returns the record for key, or a zero Record if there is none
+	Get(key string) (*Record, error)
+	//Fail atomically increments the failures for key and sets its last
+	//failure to now. The record expires after ttl without failures.
+	Fail(key string, ttl time.Duration) (*Record, error)
+	//Reset deletes the record for key
+	Reset(key string) error
+}
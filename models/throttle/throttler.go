@@ -0,0 +1,96 @@
+//Package throttle tracks failed attempts, such as sign-in failures, and
+//locks out further attempts with an exponentially-increasing delay
+package throttle
+
+import (
+	"fmt"
+	"time"
+)
+
+//Record is the failure history for a key
+type Record struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+}
+
+//Policy determines when and for how long a key is locked out
+type Policy struct {
+	//FreeAttempts is the number of failures allowed before lockouts begin
+	FreeAttempts int
+	//BaseDelay is the lockout after the first failure beyond FreeAttempts;
+	//it doubles with each subsequent failure
+	BaseDelay time.Duration
+	//MaxDelay is the maximum lockout
+	MaxDelay time.Duration
+	//Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+//delay returns the lockout following the given number of failures
+func (p *Policy) delay(failures int) time.Duration {
+	excess := failures - p.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < excess && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+//Throttler applies a Policy to the failures recorded in a Store.
+//Keys are prefixed so that several Throttlers can share a Store.
+type Throttler struct {
+	store  Store
+	prefix string
+	policy Policy
+}
+
+//NewThrottler constructs a new Throttler
+func NewThrottler(store Store, prefix string, policy Policy) *Throttler {
+	return &Throttler{
+		store:  store,
+		prefix: prefix,
+		policy: policy,
+	}
+}
+
+//Check returns how long the caller must wait before key may be attempted
+//again, or zero if it's not locked out
+func (t *Throttler) Check(key string) (time.Duration, error) {
+	record, err := t.store.Get(t.prefix + key)
+	if err != nil {
+		return 0, fmt.Errorf("error checking throttle: %v", err)
+	}
+	return t.retryAfter(record), nil
+}
+
+//Fail records a failed attempt for key and returns the resulting lockout
+func (t *Throttler) Fail(key string) (time.Duration, error) {
+	record, err := t.store.Fail(t.prefix+key, t.policy.Window)
+	if err != nil {
+		return 0, fmt.Errorf("error recording failure: %v", err)
+	}
+	return t.retryAfter(record), nil
+}
+
+//Reset clears the failures recorded for key
+func (t *Throttler) Reset(key string) error {
+	if err := t.store.Reset(t.prefix + key); err != nil {
+		return fmt.Errorf("error resetting throttle: %v", err)
+	}
+	return nil
+}
+
+//retryAfter returns the time remaining in the lockout for record
+func (t *Throttler) retryAfter(record *Record) time.Duration {
+	remaining := time.Until(record.LastFailure.Add(t.policy.delay(record.Failures)))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
@@ -0,0 +1,275 @@
+package users
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+//boltUsersBucket is the name of the bucket holding user records
+var boltUsersBucket = []byte("users")
+
+//BoltStore is an implementation of the Store interface that persists
+//users to a local bbolt database file. It requires no external database,
+//so it is suitable for small, single-node deployments. Only one process
+//may have the database file open at a time.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+//NewBoltStore opens (or creates) the bbolt database file at path and
+//constructs a new BoltStore that uses it
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening bolt database '%s': %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltUsersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating users bucket: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+//DB returns the underlying database, so that other stores can share
+//the file, which only one process may have open at a time
+func (bs *BoltStore) DB() *bolt.DB {
+	return bs.db
+}
+
+//Close closes the underlying database file
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
+
+//Get returns the user associated with the provided userName
+func (bs *BoltStore) Get(userName string) (*User, error) {
+	var user *User
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		user, err = boltGetUser(tx, userName)
+		return err
+	})
+	if err != nil {
+		return nil, boltErr("getting user", err)
+	}
+	return user, nil
+}
+
+//GetByVerifiedEmail returns the user associated with the provided verified
+//email. This reads every user record, as bbolt has no secondary indexes.
+func (bs *BoltStore) GetByVerifiedEmail(email string) (*User, error) {
+	var user *User
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUsersBucket).ForEach(func(k []byte, v []byte) error {
+			if user != nil {
+				return nil
+			}
+			u, err := boltGetUser(tx, string(k))
+			if err != nil {
+				return err
+			}
+			if u.EmailVerified && strings.EqualFold(u.Email, email) {
+				user = u
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltErr("getting user by email", err)
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+//GetByWebAuthnHandle returns the user associated with the provided WebAuthn
+//user handle. Like GetByVerifiedEmail, this reads every user record.
+func (bs *BoltStore) GetByWebAuthnHandle(handle []byte) (*User, error) {
+	var user *User
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUsersBucket).ForEach(func(k []byte, v []byte) error {
+			if user != nil {
+				return nil
+			}
+			u, err := boltGetUser(tx, string(k))
+			if err != nil {
+				return err
+			}
+			if len(u.WebAuthnHandle) > 0 && bytes.Equal(u.WebAuthnHandle, handle) {
+				user = u
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltErr("getting user by WebAuthn handle", err)
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+//Insert inserts a new user into the store
+func (bs *BoltStore) Insert(user *User) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltUsersBucket).Get([]byte(user.UserName)) != nil {
+			return ErrUserNameTaken
+		}
+		user.Version = 1
+		return boltPutUser(tx, user)
+	})
+	if err != nil {
+		return boltErr("inserting user", err)
+	}
+	return nil
+}
+
+//Update updates properties of an existing user
+func (bs *BoltStore) Update(userName string, updates *Updates, version int) (*User, error) {
+	var user *User
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		user, err = boltGetUser(tx, userName)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, version); err != nil {
+			return err
+		}
+		if err := user.applyUpdates(updates); err != nil {
+			return err
+		}
+		user.Version++
+		return boltPutUser(tx, user)
+	})
+	if err != nil {
+		return nil, boltErr("updating user", err)
+	}
+	return user, nil
+}
+
+//Save replaces the stored user record
+func (bs *BoltStore) Save(user *User) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		stored, err := boltGetUser(tx, user.UserName)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(stored, user.Version); err != nil {
+			return err
+		}
+		saved := *user
+		saved.Version = stored.Version + 1
+		if err := boltPutUser(tx, &saved); err != nil {
+			return err
+		}
+		user.Version = saved.Version
+		return nil
+	})
+	if err != nil {
+		return boltErr("saving user", err)
+	}
+	return nil
+}
+
+//Delete deletes the user
+func (bs *BoltStore) Delete(userName string, version int) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		user, err := boltGetUser(tx, userName)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, version); err != nil {
+			return err
+		}
+		return tx.Bucket(boltUsersBucket).Delete([]byte(userName))
+	})
+	if err != nil {
+		return boltErr("deleting user", err)
+	}
+	return nil
+}
+
+//ListPurgeable returns the names of up to limit users whose grace periods
+//have passed at now. This reads every user record, as bbolt has no
+//secondary indexes.
+func (bs *BoltStore) ListPurgeable(now time.Time, limit int) ([]string, error) {
+	userNames := []string{}
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUsersBucket).ForEach(func(k []byte, v []byte) error {
+			if len(userNames) == limit {
+				return nil
+			}
+			user, err := decodeGobUser(v)
+			if err != nil {
+				return err
+			}
+			if user.Purgeable(now) {
+				userNames = append(userNames, user.UserName)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltErr("listing purgeable users", err)
+	}
+	return userNames, nil
+}
+
+//boltGetUser reads and decodes the user record for userName,
+//returning ErrNotFound if there is no such user
+func boltGetUser(tx *bolt.Tx, userName string) (*User, error) {
+	val := tx.Bucket(boltUsersBucket).Get([]byte(userName))
+	if val == nil {
+		return nil, ErrNotFound
+	}
+	return decodeGobUser(val)
+}
+
+//decodeGobUser decodes a gob-encoded user record, mapping the
+//legacy disabled flag of older records to StatusSuspended
+func decodeGobUser(val []byte) (*User, error) {
+	user := &User{}
+	if err := gob.NewDecoder(bytes.NewReader(val)).Decode(user); err != nil {
+		return nil, fmt.Errorf("error decoding user record: %v", err)
+	}
+	//gob ignores the fields the record has that User doesn't,
+	//so the legacy flag must be decoded separately
+	legacy := &legacyStatus{}
+	if err := gob.NewDecoder(bytes.NewReader(val)).Decode(legacy); err != nil {
+		return nil, fmt.Errorf("error decoding user record: %v", err)
+	}
+	legacy.apply(user)
+	return user, nil
+}
+
+//boltPutUser encodes and writes the user record
+func boltPutUser(tx *bolt.Tx, user *User) error {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(user); err != nil {
+		return fmt.Errorf("error encoding user: %v", err)
+	}
+	return tx.Bucket(boltUsersBucket).Put([]byte(user.UserName), buf.Bytes())
+}
+
+//boltErr returns the Store errors as-is, and wraps all
+//others so that they match ErrUnavailable
+func boltErr(action string, err error) error {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrNothingToUpdate) || errors.Is(err, ErrVersionMismatch) {
+		return err
+	}
+	return unavailable(action, err)
+}
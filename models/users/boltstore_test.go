@@ -0,0 +1,50 @@
+package users
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltstore")
+	if err != nil {
+		t.Fatalf("error creating temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("error creating BoltStore: %v", err)
+	}
+	testStore(t, store)
+
+	//users must survive closing and re-opening the database
+	user := &User{UserName: "persistent", PasswordHash: []byte("hash"), Email: "test@test.com"}
+	if err := store.Insert(user); err != nil {
+		t.Fatalf("error inserting user: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("error closing BoltStore: %v", err)
+	}
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("error re-opening BoltStore: %v", err)
+	}
+	gotUser, err := store.Get(user.UserName)
+	if err != nil {
+		t.Fatalf("error getting user after re-opening: %v", err)
+	}
+	if gotUser == nil || gotUser.Email != user.Email {
+		t.Errorf("user was not persisted across restarts: expected %+v but got %+v", user, gotUser)
+	}
+
+	//failures of the database itself are reported as ErrUnavailable
+	store.Close()
+	if _, err := store.Get(user.UserName); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("incorrect error when database is closed: expected %v but got %v", ErrUnavailable, err)
+	}
+}
@@ -0,0 +1,37 @@
+package users
+
+import "strings"
+
+//StandardClaims are the OpenID Connect standard claims about a user,
+//other than the subject, which is the user name
+type StandardClaims struct {
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+//StandardClaims returns the standard claims about the user that the
+//scopes permit a client to see: the "profile" scope permits the
+//names, and the "email" scope permits the email address
+func (u *User) StandardClaims(scopes []string) *StandardClaims {
+	claims := &StandardClaims{}
+	for _, scope := range scopes {
+		switch scope {
+		case "profile":
+			claims.PreferredUsername = u.UserName
+			claims.Name = strings.TrimSpace(u.PersonalName + " " + u.FamilyName)
+			claims.GivenName = u.PersonalName
+			claims.FamilyName = u.FamilyName
+		case "email":
+			if len(u.Email) > 0 {
+				verified := u.EmailVerified
+				claims.Email = u.Email
+				claims.EmailVerified = &verified
+			}
+		}
+	}
+	return claims
+}
@@ -0,0 +1,35 @@
+package users
+
+import "testing"
+
+func TestStandardClaims(t *testing.T) {
+	u := &User{
+		UserName:      "tester",
+		PersonalName:  "Test",
+		FamilyName:    "User",
+		Email:         "test@example.com",
+		EmailVerified: true,
+	}
+
+	claims := u.StandardClaims([]string{"openid"})
+	if len(claims.Name) > 0 || len(claims.Email) > 0 {
+		t.Errorf("claims must be empty without the profile or email scopes: %+v", claims)
+	}
+
+	claims = u.StandardClaims([]string{"openid", "profile"})
+	if claims.PreferredUsername != "tester" || claims.Name != "Test User" ||
+		claims.GivenName != "Test" || claims.FamilyName != "User" {
+		t.Errorf("incorrect profile claims: %+v", claims)
+	}
+	if len(claims.Email) > 0 {
+		t.Errorf("email must not be included without the email scope")
+	}
+
+	claims = u.StandardClaims([]string{"openid", "email"})
+	if claims.Email != "test@example.com" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("incorrect email claims: %+v", claims)
+	}
+	if len(claims.GivenName) > 0 {
+		t.Errorf("names must not be included without the profile scope")
+	}
+}
@@ -1,10 +1,14 @@
 package users
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
@@ -34,20 +38,89 @@ func (d *DynamoDBStore) Get(userName string) (*User, error) {
 	}
 	result, err := d.client.GetItem(input)
 	if err != nil {
-		return nil, fmt.Errorf("error getting user: %v", err)
+		return nil, unavailable("getting user", err)
 	}
 	if result.Item == nil {
-		return nil, nil
+		return nil, ErrNotFound
 	}
-	user := &User{}
-	if err := dynamodbattribute.UnmarshalMap(result.Item, user); err != nil {
-		return nil, fmt.Errorf("error decoding user record: %v", err)
+	return decodeDynamoUser(result.Item)
+}
+
+//GetByVerifiedEmail returns the user associated with the provided verified
+//email. The table has no index on email, and matching must be case-insensitive,
+//so this scans the table; it should be used only for infrequent operations
+//such as password resets.
+func (d *DynamoDBStore) GetByVerifiedEmail(email string) (*User, error) {
+	var user *User
+	var decodeErr error
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(d.tableName),
+	}
+	err := d.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var u *User
+			if u, decodeErr = decodeDynamoUser(item); decodeErr != nil {
+				return false
+			}
+			if u.EmailVerified && strings.EqualFold(u.Email, email) {
+				user = u
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, unavailable("scanning users", err)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+//GetByWebAuthnHandle returns the user associated with the provided WebAuthn
+//user handle. The table has no index on the handle, so this scans the table,
+//filtering on the handle; large tables should add a global secondary index.
+func (d *DynamoDBStore) GetByWebAuthnHandle(handle []byte) (*User, error) {
+	var user *User
+	var decodeErr error
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(d.tableName),
+		FilterExpression:          aws.String("#handle = :handle"),
+		ExpressionAttributeNames:  map[string]*string{"#handle": aws.String("webAuthnHandle")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":handle": {B: handle}},
+	}
+	err := d.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var u *User
+			if u, decodeErr = decodeDynamoUser(item); decodeErr != nil {
+				return false
+			}
+			if len(u.WebAuthnHandle) > 0 && bytes.Equal(u.WebAuthnHandle, handle) {
+				user = u
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, unavailable("scanning users", err)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if user == nil {
+		return nil, ErrNotFound
 	}
 	return user, nil
 }
 
 //Insert inserts a new user into the store
 func (d *DynamoDBStore) Insert(user *User) error {
+	user.Version = 1
 	vals, err := dynamodbattribute.MarshalMap(user)
 	if err != nil {
 		return fmt.Errorf("error encoding user: %v", err)
@@ -58,67 +131,218 @@ func (d *DynamoDBStore) Insert(user *User) error {
 		vals[d.keyName] = &dynamodb.AttributeValue{S: aws.String(user.UserName)}
 	}
 
+	//the condition ensures we never overwrite an existing user
 	input := &dynamodb.PutItemInput{
-		TableName: aws.String(d.tableName),
-		Item:      vals,
+		TableName:                aws.String(d.tableName),
+		Item:                     vals,
+		ConditionExpression:      aws.String("attribute_not_exists(#key)"),
+		ExpressionAttributeNames: map[string]*string{"#key": aws.String(d.keyName)},
 	}
 	if _, err := d.client.PutItem(input); err != nil {
-		return fmt.Errorf("error inserting user: %v", err)
+		if isConditionFailed(err) {
+			return ErrUserNameTaken
+		}
+		return unavailable("inserting user", err)
 	}
 	return nil
 }
 
 //Update updates properties of an existing user
-func (d *DynamoDBStore) Update(userName string, updates *Updates) (*User, error) {
+func (d *DynamoDBStore) Update(userName string, updates *Updates, version int) (*User, error) {
 	vals, err := dynamodbattribute.MarshalMap(updates)
 	if err != nil {
 		return nil, fmt.Errorf("error encoding user updates: %v", err)
 	}
 	if len(vals) == 0 {
-		return nil, fmt.Errorf("nothing to update")
+		return nil, ErrNothingToUpdate
 	}
 
-	var exprs []string
-	exprNames := map[string]*string{}
-	exprValues := map[string]*dynamodb.AttributeValue{}
+	//records written before versioning was added have no version attribute
+	exprs := []string{"#version = if_not_exists(#version, :zero) + :one"}
+	exprNames := map[string]*string{"#key": aws.String(d.keyName), "#version": aws.String("version")}
+	exprValues := map[string]*dynamodb.AttributeValue{
+		":zero": {N: aws.String("0")},
+		":one":  {N: aws.String("1")},
+	}
 	for k, v := range vals {
 		exprs = append(exprs, fmt.Sprintf("%s = %s", "#"+k, ":"+k))
 		exprNames["#"+k] = aws.String(k)
 		exprValues[":"+k] = v
 	}
+	//a new email address or mobile number must be verified again
+	if updates.Email != nil {
+		exprs = append(exprs, "#emailVerified = :false")
+		exprNames["#emailVerified"] = aws.String("emailVerified")
+		exprValues[":false"] = &dynamodb.AttributeValue{BOOL: aws.Bool(false)}
+	}
+	if updates.Mobile != nil {
+		exprs = append(exprs, "#mobileVerified = :false")
+		exprNames["#mobileVerified"] = aws.String("mobileVerified")
+		exprValues[":false"] = &dynamodb.AttributeValue{BOOL: aws.Bool(false)}
+	}
 
 	input := &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(d.tableName),
 		Key:                       d.getKey(userName),
 		UpdateExpression:          aws.String("SET " + strings.Join(exprs, ", ")),
+		ConditionExpression:       aws.String(d.versionCondition(version, exprValues)),
 		ExpressionAttributeNames:  exprNames,
 		ExpressionAttributeValues: exprValues,
 		ReturnValues:              aws.String("ALL_NEW"),
 	}
 
+	//without the condition, DynamoDB would create a new item
+	//if the user doesn't already exist
 	result, err := d.client.UpdateItem(input)
 	if err != nil {
-		return nil, fmt.Errorf("error updating user: %v", err)
+		if isConditionFailed(err) {
+			return nil, d.notFoundOrMismatch(userName)
+		}
+		return nil, unavailable("updating user", err)
 	}
-	user := &User{}
-	if err := dynamodbattribute.UnmarshalMap(result.Attributes, user); err != nil {
-		return nil, fmt.Errorf("error decoding user record: %v", err)
+	return decodeDynamoUser(result.Attributes)
+}
+
+//Save replaces the stored user record
+func (d *DynamoDBStore) Save(user *User) error {
+	saved := *user
+	saved.Version = user.Version + 1
+	vals, err := dynamodbattribute.MarshalMap(&saved)
+	if err != nil {
+		return fmt.Errorf("error encoding user: %v", err)
 	}
-	return user, nil
+	if _, found := vals[d.keyName]; !found {
+		vals[d.keyName] = &dynamodb.AttributeValue{S: aws.String(user.UserName)}
+	}
+
+	exprValues := map[string]*dynamodb.AttributeValue{}
+	input := &dynamodb.PutItemInput{
+		TableName:                 aws.String(d.tableName),
+		Item:                      vals,
+		ConditionExpression:       aws.String(d.versionCondition(user.Version, exprValues)),
+		ExpressionAttributeNames:  map[string]*string{"#key": aws.String(d.keyName), "#version": aws.String("version")},
+		ExpressionAttributeValues: exprValues,
+	}
+	if user.Version == AnyVersion {
+		//the version is unknown, so read it to compute the next one
+		stored, err := d.Get(user.UserName)
+		if err != nil {
+			return err
+		}
+		saved.Version = stored.Version + 1
+		vals["version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(saved.Version))}
+		delete(input.ExpressionAttributeNames, "#version")
+		input.ExpressionAttributeValues = nil
+	}
+	if _, err := d.client.PutItem(input); err != nil {
+		if isConditionFailed(err) {
+			return d.notFoundOrMismatch(user.UserName)
+		}
+		return unavailable("saving user", err)
+	}
+	user.Version = saved.Version
+	return nil
 }
 
 //Delete deletes the user
-func (d *DynamoDBStore) Delete(userName string) error {
+func (d *DynamoDBStore) Delete(userName string, version int) error {
+	exprValues := map[string]*dynamodb.AttributeValue{}
 	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(d.tableName),
-		Key:       d.getKey(userName),
+		TableName:                aws.String(d.tableName),
+		Key:                      d.getKey(userName),
+		ConditionExpression:      aws.String(d.versionCondition(version, exprValues)),
+		ExpressionAttributeNames: map[string]*string{"#key": aws.String(d.keyName), "#version": aws.String("version")},
+	}
+	//DynamoDB rejects requests that supply unused expression values
+	if len(exprValues) > 0 {
+		input.ExpressionAttributeValues = exprValues
+	} else {
+		delete(input.ExpressionAttributeNames, "#version")
 	}
 	if _, err := d.client.DeleteItem(input); err != nil {
-		return fmt.Errorf("error deleting user: %v", err)
+		if isConditionFailed(err) {
+			return d.notFoundOrMismatch(userName)
+		}
+		return unavailable("deleting user", err)
 	}
 	return nil
 }
 
+//ListPurgeable returns the names of up to limit users whose grace periods
+//have passed at now. Like GetByVerifiedEmail, this scans the table, which is
+//acceptable only because the purger runs infrequently.
+func (d *DynamoDBStore) ListPurgeable(now time.Time, limit int) ([]string, error) {
+	userNames := []string{}
+	var decodeErr error
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(d.tableName),
+	}
+	err := d.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var user *User
+			if user, decodeErr = decodeDynamoUser(item); decodeErr != nil {
+				return false
+			}
+			if user.Purgeable(now) {
+				userNames = append(userNames, user.UserName)
+				if len(userNames) == limit {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, unavailable("scanning users", err)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return userNames, nil
+}
+
+//decodeDynamoUser decodes a DynamoDB user item, mapping the
+//legacy disabled attribute of older items to StatusSuspended
+func decodeDynamoUser(item map[string]*dynamodb.AttributeValue) (*User, error) {
+	user := &User{}
+	if err := dynamodbattribute.UnmarshalMap(item, user); err != nil {
+		return nil, fmt.Errorf("error decoding user record: %v", err)
+	}
+	legacy := &legacyStatus{}
+	if err := dynamodbattribute.UnmarshalMap(item, legacy); err != nil {
+		return nil, fmt.Errorf("error decoding user record: %v", err)
+	}
+	legacy.apply(user)
+	return user, nil
+}
+
+//versionCondition returns a ConditionExpression requiring that the user
+//exists and, unless version is AnyVersion, that its version matches.
+//Any values referenced by the expression are added to exprValues.
+func (d *DynamoDBStore) versionCondition(version int, exprValues map[string]*dynamodb.AttributeValue) string {
+	if version == AnyVersion {
+		return "attribute_exists(#key)"
+	}
+	exprValues[":version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(version))}
+	return "attribute_exists(#key) AND #version = :version"
+}
+
+//notFoundOrMismatch determines why a conditional write failed:
+//either the user doesn't exist, or its version didn't match
+func (d *DynamoDBStore) notFoundOrMismatch(userName string) error {
+	if _, err := d.Get(userName); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+//isConditionFailed returns true if err indicates that
+//the ConditionExpression of a write request was not met
+func isConditionFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
 func (d *DynamoDBStore) getKey(userName string) map[string]*dynamodb.AttributeValue {
 	return map[string]*dynamodb.AttributeValue{d.keyName: {S: aws.String(userName)}}
 }
@@ -0,0 +1,37 @@
+package users
+
+import (
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+//NewExternalUser constructs a new User for someone signing up via an
+//external identity provider. The user has no password hash, so they can
+//sign in only via the provider until they set a password by resetting it.
+//The email address is considered verified if the provider verified it.
+func NewExternalUser(userName string, personalName string, familyName string, email string, emailVerified bool) (*User, error) {
+	if len(userName) == 0 {
+		return nil, fmt.Errorf("userName must be supplied")
+	}
+	if len(email) > 0 {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return nil, fmt.Errorf("invalid email address: %v", err)
+		}
+	}
+	return &User{
+		UserName:      userName,
+		PersonalName:  personalName,
+		FamilyName:    familyName,
+		Email:         email,
+		EmailVerified: len(email) > 0 && emailVerified,
+		//the name may have belonged to a purged account, whose
+		//sessions and tokens must not be valid for this one
+		CredentialsChanged: time.Now().UTC().Truncate(time.Microsecond),
+	}, nil
+}
+
+//HasPassword returns true if the user has a password
+func (u *User) HasPassword() bool {
+	return len(u.PasswordHash) > 0
+}
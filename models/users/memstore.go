@@ -0,0 +1,158 @@
+package users
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+//MemStore is an in-memory implementation of the Store interface.
+//It is safe for concurrent use, but all data is lost when the process
+//exits, so it should be used only for automated tests and local development.
+type MemStore struct {
+	mx    sync.RWMutex
+	users map[string]*User
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		users: map[string]*User{},
+	}
+}
+
+//Get returns the user associated with the provided userName
+func (ms *MemStore) Get(userName string) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	user, found := ms.users[userName]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+//GetByVerifiedEmail returns the user associated with the provided verified email
+func (ms *MemStore) GetByVerifiedEmail(email string) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	for _, user := range ms.users {
+		if user.EmailVerified && strings.EqualFold(user.Email, email) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+//GetByWebAuthnHandle returns the user associated with the provided WebAuthn user handle
+func (ms *MemStore) GetByWebAuthnHandle(handle []byte) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	for _, user := range ms.users {
+		if len(user.WebAuthnHandle) > 0 && bytes.Equal(user.WebAuthnHandle, handle) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+//Insert inserts a new user into the store
+func (ms *MemStore) Insert(user *User) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, found := ms.users[user.UserName]; found {
+		return ErrUserNameTaken
+	}
+	user.Version = 1
+	ms.users[user.UserName] = copyUser(user)
+	return nil
+}
+
+//Update updates properties of an existing user
+func (ms *MemStore) Update(userName string, updates *Updates, version int) (*User, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[userName]
+	if !found {
+		return nil, ErrNotFound
+	}
+	if err := checkVersion(user, version); err != nil {
+		return nil, err
+	}
+	if err := user.applyUpdates(updates); err != nil {
+		return nil, err
+	}
+	user.Version++
+	return copyUser(user), nil
+}
+
+//Save replaces the stored user record
+func (ms *MemStore) Save(user *User) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	stored, found := ms.users[user.UserName]
+	if !found {
+		return ErrNotFound
+	}
+	if err := checkVersion(stored, user.Version); err != nil {
+		return err
+	}
+	user.Version = stored.Version + 1
+	ms.users[user.UserName] = copyUser(user)
+	return nil
+}
+
+//Delete deletes the user
+func (ms *MemStore) Delete(userName string, version int) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[userName]
+	if !found {
+		return ErrNotFound
+	}
+	if err := checkVersion(user, version); err != nil {
+		return err
+	}
+	delete(ms.users, userName)
+	return nil
+}
+
+//ListPurgeable returns the names of up to limit users
+//whose grace periods have passed at now
+func (ms *MemStore) ListPurgeable(now time.Time, limit int) ([]string, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	userNames := []string{}
+	for userName, user := range ms.users {
+		if len(userNames) == limit {
+			break
+		}
+		if user.Purgeable(now) {
+			userNames = append(userNames, userName)
+		}
+	}
+	return userNames, nil
+}
+
+//copyUser returns a deep copy of user so that callers
+//can't modify the records held in the store
+func copyUser(user *User) *User {
+	c := *user
+	if user.PasswordHash != nil {
+		c.PasswordHash = append([]byte(nil), user.PasswordHash...)
+	}
+	if user.TOTPSecret != nil {
+		c.TOTPSecret = append([]byte(nil), user.TOTPSecret...)
+	}
+	if user.RecoveryCodes != nil {
+		c.RecoveryCodes = append([]string(nil), user.RecoveryCodes...)
+	}
+	if user.Passkeys != nil {
+		c.Passkeys = append(Passkeys(nil), user.Passkeys...)
+	}
+	if user.WebAuthnHandle != nil {
+		c.WebAuthnHandle = append([]byte(nil), user.WebAuthnHandle...)
+	}
+	return &c
+}
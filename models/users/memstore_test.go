@@ -0,0 +1,36 @@
+package users
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
+
+func TestMemStoreConcurrency(t *testing.T) {
+	store := NewMemStore()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userName := fmt.Sprintf("user-%d", i)
+			if err := store.Insert(&User{UserName: userName}); err != nil {
+				t.Errorf("error inserting user %s: %v", userName, err)
+			}
+			if _, err := store.Get(userName); err != nil {
+				t.Errorf("error getting user %s: %v", userName, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 50; i++ {
+		userName := fmt.Sprintf("user-%d", i)
+		if user, _ := store.Get(userName); user == nil {
+			t.Errorf("user %s was not found after concurrent inserts", userName)
+		}
+	}
+}
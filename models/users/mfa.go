@@ -0,0 +1,179 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davestearns/userservice/sealing"
+	"github.com/davestearns/userservice/totp"
+)
+
+//recoveryCodeCount is the number of recovery codes issued when TOTP is enabled
+const recoveryCodeCount = 10
+
+//recoveryCodeEncoding is the encoding used for recovery codes, which
+//avoids characters that are easily confused when read back by a person
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghjkmnpqrstuvwxyz023456789").WithPadding(base32.NoPadding)
+
+//ErrInvalidMFACode is returned when a TOTP or recovery code is incorrect
+var ErrInvalidMFACode = errors.New("invalid authentication code")
+
+//ErrTOTPNotEnrolled is returned when confirming a TOTP enrollment
+//that was never started
+var ErrTOTPNotEnrolled = errors.New("TOTP enrollment has not been started")
+
+//TOTPEnrollment is returned when a user begins TOTP enrollment.
+//The Secret and URI should be entered into an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+//MFACode represents a TOTP or recovery code sent by the client
+type MFACode struct {
+	Code string `json:"code"`
+}
+
+//Validate validates the MFACode
+func (mc *MFACode) Validate() error {
+	if len(mc.Code) == 0 {
+		return fmt.Errorf("code must be supplied")
+	}
+	return nil
+}
+
+//MFAChallengeResponse completes a pending-MFA sign-in
+type MFAChallengeResponse struct {
+	//Challenge is the token returned from the first sign-in step
+	Challenge string `json:"challenge"`
+	//Code is a TOTP or recovery code
+	Code string `json:"code"`
+}
+
+//Validate validates the MFAChallengeResponse
+func (mr *MFAChallengeResponse) Validate() error {
+	if len(mr.Challenge) == 0 {
+		return fmt.Errorf("challenge must be supplied")
+	}
+	if len(mr.Code) == 0 {
+		return fmt.Errorf("code must be supplied")
+	}
+	return nil
+}
+
+//BeginTOTPEnrollment generates a new TOTP secret for the user, storing it
+//sealed but not yet enabled. This changes only the in-memory user:
+//use Store.Save to persist the change.
+func (u *User) BeginTOTPEnrollment(sealer *sealing.Sealer, issuer string) (*TOTPEnrollment, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealer.Seal([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("error sealing TOTP secret: %v", err)
+	}
+	u.TOTPSecret = sealed
+	u.TOTPEnabled = false
+	u.TOTPLastStep = 0
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(issuer, u.UserName, secret),
+	}, nil
+}
+
+//ConfirmTOTPEnrollment enables TOTP if code is valid for the pending secret,
+//and returns a new set of plaintext recovery codes, which should be shown to
+//the user only once. This changes only the in-memory user: use Store.Save
+//to persist the change.
+func (u *User) ConfirmTOTPEnrollment(sealer *sealing.Sealer, code string) ([]string, error) {
+	if len(u.TOTPSecret) == 0 {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := u.verifyTOTP(sealer, code); err != nil {
+		return nil, err
+	}
+	plaintexts, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	u.TOTPEnabled = true
+	u.RecoveryCodes = hashes
+	return plaintexts, nil
+}
+
+//DisableTOTP removes the user's TOTP secret and recovery codes.
+//This changes only the in-memory user: use Store.Save to persist the change.
+func (u *User) DisableTOTP() {
+	u.TOTPSecret = nil
+	u.TOTPEnabled = false
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = nil
+}
+
+//VerifyMFA verifies a TOTP code or an unused recovery code. Codes are
+//single-use, so this changes the in-memory user even when it returns
+//nil: use Store.Save to persist the change before trusting the result.
+func (u *User) VerifyMFA(sealer *sealing.Sealer, code string) error {
+	if !u.TOTPEnabled {
+		return ErrInvalidMFACode
+	}
+	if err := u.verifyTOTP(sealer, code); err != ErrInvalidMFACode {
+		return err
+	}
+	hash := hashRecoveryCode(code)
+	for i, rc := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(rc), []byte(hash)) == 1 {
+			u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvalidMFACode
+}
+
+//verifyTOTP verifies code against the user's TOTP secret, rejecting
+//codes from time steps that have already been used
+func (u *User) verifyTOTP(sealer *sealing.Sealer, code string) error {
+	secret, err := sealer.Open(u.TOTPSecret)
+	if err != nil {
+		return fmt.Errorf("error opening TOTP secret: %v", err)
+	}
+	step, ok := totp.Validate(string(secret), strings.TrimSpace(code), time.Now())
+	if !ok || step <= u.TOTPLastStep {
+		return ErrInvalidMFACode
+	}
+	u.TOTPLastStep = step
+	return nil
+}
+
+//newRecoveryCodes generates a new set of recovery codes,
+//returning both the plaintext codes and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	var plaintexts, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery code: %v", err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(buf)
+		plaintext := code[:4] + "-" + code[4:]
+		plaintexts = append(plaintexts, plaintext)
+		hashes = append(hashes, hashRecoveryCode(plaintext))
+	}
+	return plaintexts, hashes, nil
+}
+
+//hashRecoveryCode returns the hex-encoded SHA-256 hash of the normalized code.
+//Recovery codes are random, so a fast hash is sufficient.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package users
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davestearns/userservice/sealing"
+	"github.com/davestearns/userservice/totp"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	sealer, err := sealing.NewSealer([]string{"test-key"})
+	if err != nil {
+		t.Fatalf("error constructing sealer: %v", err)
+	}
+	user := &User{UserName: "tester"}
+	if _, err := user.ConfirmTOTPEnrollment(sealer, "123456"); err != ErrTOTPNotEnrolled {
+		t.Errorf("incorrect error confirming without enrolling: expected %v but got %v", ErrTOTPNotEnrolled, err)
+	}
+
+	enrollment, err := user.BeginTOTPEnrollment(sealer, "test")
+	if err != nil {
+		t.Fatalf("error beginning enrollment: %v", err)
+	}
+	if string(user.TOTPSecret) == enrollment.Secret {
+		t.Errorf("TOTP secret was stored unsealed")
+	}
+	if user.TOTPEnabled {
+		t.Errorf("TOTP should not be enabled until enrollment is confirmed")
+	}
+
+	now := time.Now()
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+	recoveryCodes, err := user.ConfirmTOTPEnrollment(sealer, code)
+	if err != nil {
+		t.Fatalf("error confirming enrollment: %v", err)
+	}
+	if !user.TOTPEnabled {
+		t.Errorf("TOTP was not enabled after confirmation")
+	}
+	if len(recoveryCodes) != recoveryCodeCount || len(user.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("incorrect number of recovery codes: expected %d but got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+
+	//codes can't be reused
+	if err := user.VerifyMFA(sealer, code); err != ErrInvalidMFACode {
+		t.Errorf("incorrect error reusing TOTP code: expected %v but got %v", ErrInvalidMFACode, err)
+	}
+	if err := user.VerifyMFA(sealer, recoveryCodes[0]); err != nil {
+		t.Errorf("error verifying recovery code: %v", err)
+	}
+	if err := user.VerifyMFA(sealer, recoveryCodes[0]); err != ErrInvalidMFACode {
+		t.Errorf("incorrect error reusing recovery code: expected %v but got %v", ErrInvalidMFACode, err)
+	}
+	if len(user.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Errorf("used recovery code was not removed")
+	}
+
+	user.DisableTOTP()
+	if user.TOTPEnabled || user.TOTPSecret != nil || user.RecoveryCodes != nil {
+		t.Errorf("DisableTOTP did not clear TOTP fields: %+v", user)
+	}
+}
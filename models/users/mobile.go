@@ -0,0 +1,51 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+//DefaultCallingCode is the country calling code assumed for mobile
+//numbers that are not supplied in international format
+var DefaultCallingCode = "1"
+
+//mobileSeparators are the characters people commonly use
+//to format phone numbers, which are removed during normalization
+var mobileSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "/", "")
+
+//NormalizeMobile converts a mobile number to E.164 format (e.g., +12065551212).
+//Numbers starting with + or the international prefix 00 are treated as
+//international; all others are treated as national numbers within the
+//country identified by DefaultCallingCode.
+func NormalizeMobile(mobile string) (string, error) {
+	number := mobileSeparators.Replace(strings.TrimSpace(mobile))
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case DefaultCallingCode == "1":
+		//North American numbers may be written with the leading 1
+		if len(number) == 11 && number[0] == '1' {
+			number = number[1:]
+		}
+		if len(number) != 10 {
+			return "", fmt.Errorf("invalid mobile number '%s': must have 10 digits", mobile)
+		}
+		number = DefaultCallingCode + number
+	default:
+		//remove the national trunk prefix
+		number = DefaultCallingCode + strings.TrimPrefix(number, "0")
+	}
+
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("invalid mobile number '%s': must contain only digits and separators", mobile)
+		}
+	}
+	//E.164 numbers have at most 15 digits, and no calling code starts with 0
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", fmt.Errorf("invalid mobile number '%s'", mobile)
+	}
+	return "+" + number, nil
+}
@@ -0,0 +1,41 @@
+package users
+
+import "testing"
+
+func TestNormalizeMobile(t *testing.T) {
+	cases := []struct {
+		name        string
+		callingCode string
+		input       string
+		expected    string
+		expectErr   bool
+	}{
+		{"US dashes", "1", "206-555-1212", "+12065551212", false},
+		{"US parens", "1", "(206) 555-1212", "+12065551212", false},
+		{"US leading 1", "1", "1 206 555 1212", "+12065551212", false},
+		{"already E.164", "1", "+12065551212", "+12065551212", false},
+		{"international prefix", "1", "0044 7911 123456", "+447911123456", false},
+		{"UK national", "44", "07911 123456", "+447911123456", false},
+		{"too short", "1", "555-1212", "", true},
+		{"too long", "1", "+1234567890123456", "", true},
+		{"letters", "1", "206-555-CALL", "", true},
+		{"empty", "1", "", "", true},
+	}
+
+	defer func(code string) { DefaultCallingCode = code }(DefaultCallingCode)
+	for _, c := range cases {
+		DefaultCallingCode = c.callingCode
+		got, err := NormalizeMobile(c.input)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("case %s: expected error but got %s", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", c.name, err)
+		} else if got != c.expected {
+			t.Errorf("case %s: expected %s but got %s", c.name, c.expected, got)
+		}
+	}
+}
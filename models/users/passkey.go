@@ -0,0 +1,170 @@
+package users
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//ErrPasskeyNotFound is returned when a user has no passkey with a given ID
+var ErrPasskeyNotFound = fmt.Errorf("%w: passkey not found", ErrNotFound)
+
+//ErrPasskeyExists is returned when adding a passkey the user already has
+var ErrPasskeyExists = fmt.Errorf("%w: passkey is already registered", ErrConflict)
+
+//Passkey is a WebAuthn public key credential registered by a user
+type Passkey struct {
+	//ID is the credential ID assigned by the authenticator
+	ID []byte `json:"id" dynamodbav:"id"`
+	//Name is a user-supplied name for the passkey
+	Name            string   `json:"name,omitempty" dynamodbav:"name,omitempty"`
+	PublicKey       []byte   `json:"publicKey" dynamodbav:"publicKey"`
+	AttestationType string   `json:"attestationType,omitempty" dynamodbav:"attestationType,omitempty"`
+	Transports      []string `json:"transports,omitempty" dynamodbav:"transports,omitempty"`
+	AAGUID          []byte   `json:"aaguid,omitempty" dynamodbav:"aaguid,omitempty"`
+	//SignCount is the authenticator's signature counter,
+	//used to detect cloned authenticators
+	SignCount      uint32    `json:"signCount" dynamodbav:"signCount"`
+	BackupEligible bool      `json:"backupEligible" dynamodbav:"backupEligible"`
+	BackupState    bool      `json:"backupState" dynamodbav:"backupState"`
+	Created        time.Time `json:"created" dynamodbav:"created"`
+	LastUsed       time.Time `json:"lastUsed" dynamodbav:"lastUsed"`
+}
+
+//Passkeys is a list of passkeys, which is stored
+//as a single JSON value in SQL databases
+type Passkeys []Passkey
+
+//Value implements the driver.Valuer interface
+func (p Passkeys) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	j, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(j), nil
+}
+
+//Scan implements the sql.Scanner interface
+func (p *Passkeys) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into Passkeys", src)
+	}
+}
+
+//webAuthnHandleLength is the length in bytes of a WebAuthn
+//user handle, which is the most the specification allows
+const webAuthnHandleLength = 64
+
+//WebAuthnUserHandle returns the user's WebAuthn user handle. Accounts
+//whose passkeys were registered before handles were generated use their
+//user names, as those passkeys carry them.
+func (u *User) WebAuthnUserHandle() []byte {
+	if len(u.WebAuthnHandle) == 0 {
+		return []byte(u.UserName)
+	}
+	return u.WebAuthnHandle
+}
+
+//EnsureWebAuthnHandle generates a random WebAuthn user handle if the
+//user has neither a handle nor passkeys registered under their user name,
+//returning true if it did. This changes only the in-memory user: use
+//Store.Save to persist the change before registering a passkey.
+func (u *User) EnsureWebAuthnHandle() (bool, error) {
+	if len(u.WebAuthnHandle) > 0 || len(u.Passkeys) > 0 {
+		return false, nil
+	}
+	handle := make([]byte, webAuthnHandleLength)
+	if _, err := rand.Read(handle); err != nil {
+		return false, fmt.Errorf("error generating WebAuthn user handle: %v", err)
+	}
+	u.WebAuthnHandle = handle
+	return true, nil
+}
+
+//FindPasskey returns the user's passkey with the given ID
+func (u *User) FindPasskey(id []byte) (*Passkey, error) {
+	for i := range u.Passkeys {
+		if bytes.Equal(u.Passkeys[i].ID, id) {
+			return &u.Passkeys[i], nil
+		}
+	}
+	return nil, ErrPasskeyNotFound
+}
+
+//AddPasskey adds a newly-registered passkey. This changes only
+//the in-memory user: use Store.Save to persist the change.
+func (u *User) AddPasskey(passkey *Passkey) error {
+	if _, err := u.FindPasskey(passkey.ID); err == nil {
+		return ErrPasskeyExists
+	}
+	u.Passkeys = append(u.Passkeys, *passkey)
+	return nil
+}
+
+//RemovePasskey revokes the passkey with the given ID. This changes
+//only the in-memory user: use Store.Save to persist the change.
+func (u *User) RemovePasskey(id []byte) error {
+	for i := range u.Passkeys {
+		if bytes.Equal(u.Passkeys[i].ID, id) {
+			u.Passkeys = append(u.Passkeys[:i:i], u.Passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return ErrPasskeyNotFound
+}
+
+//PasskeyRegistration completes the registration of a new passkey
+type PasskeyRegistration struct {
+	//ChallengeID is the ID returned when registration began
+	ChallengeID string `json:"challengeID"`
+	//Name is an optional name for the passkey
+	Name string `json:"name,omitempty"`
+	//Credential is the PublicKeyCredential returned by navigator.credentials.create()
+	Credential json.RawMessage `json:"credential"`
+}
+
+//Validate validates the PasskeyRegistration
+func (pr *PasskeyRegistration) Validate() error {
+	if len(pr.ChallengeID) == 0 {
+		return fmt.Errorf("challengeID must be supplied")
+	}
+	if len(pr.Credential) == 0 {
+		return fmt.Errorf("credential must be supplied")
+	}
+	return nil
+}
+
+//PasskeyAssertion completes a passkey sign-in
+type PasskeyAssertion struct {
+	//ChallengeID is the ID returned when sign-in began
+	ChallengeID string `json:"challengeID"`
+	//Credential is the PublicKeyCredential returned by navigator.credentials.get()
+	Credential json.RawMessage `json:"credential"`
+	//RememberMe selects a longer-lived session
+	RememberMe bool `json:"rememberMe,omitempty"`
+}
+
+//Validate validates the PasskeyAssertion
+func (pa *PasskeyAssertion) Validate() error {
+	if len(pa.ChallengeID) == 0 {
+		return fmt.Errorf("challengeID must be supplied")
+	}
+	if len(pa.Credential) == 0 {
+		return fmt.Errorf("credential must be supplied")
+	}
+	return nil
+}
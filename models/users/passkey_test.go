@@ -0,0 +1,65 @@
+package users
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPasskeys(t *testing.T) {
+	user := &User{UserName: "tester"}
+	if err := user.AddPasskey(&Passkey{ID: []byte("one"), Name: "laptop"}); err != nil {
+		t.Fatalf("error adding passkey: %v", err)
+	}
+	if err := user.AddPasskey(&Passkey{ID: []byte("one")}); err != ErrPasskeyExists {
+		t.Errorf("incorrect error adding duplicate passkey: expected %v but got %v", ErrPasskeyExists, err)
+	}
+	user.AddPasskey(&Passkey{ID: []byte("two"), Name: "phone"})
+
+	passkey, err := user.FindPasskey([]byte("two"))
+	if err != nil {
+		t.Fatalf("error finding passkey: %v", err)
+	}
+	if passkey.Name != "phone" {
+		t.Errorf("incorrect passkey found: expected phone but got %s", passkey.Name)
+	}
+
+	if err := user.RemovePasskey([]byte("one")); err != nil {
+		t.Errorf("error removing passkey: %v", err)
+	}
+	if _, err := user.FindPasskey([]byte("one")); err != ErrPasskeyNotFound {
+		t.Errorf("incorrect error finding removed passkey: expected %v but got %v", ErrPasskeyNotFound, err)
+	}
+	if err := user.RemovePasskey([]byte("one")); err != ErrPasskeyNotFound {
+		t.Errorf("incorrect error removing passkey twice: expected %v but got %v", ErrPasskeyNotFound, err)
+	}
+	if len(user.Passkeys) != 1 {
+		t.Errorf("incorrect number of passkeys: expected 1 but got %d", len(user.Passkeys))
+	}
+}
+
+func TestWebAuthnHandle(t *testing.T) {
+	user := &User{UserName: "tester"}
+	generated, err := user.EnsureWebAuthnHandle()
+	if err != nil {
+		t.Fatalf("error generating WebAuthn handle: %v", err)
+	}
+	if !generated || len(user.WebAuthnHandle) != webAuthnHandleLength {
+		t.Fatalf("incorrect WebAuthn handle generated: got %d bytes", len(user.WebAuthnHandle))
+	}
+	if !bytes.Equal(user.WebAuthnUserHandle(), user.WebAuthnHandle) {
+		t.Errorf("user handle is not the generated handle")
+	}
+	handle := user.WebAuthnHandle
+	if generated, err := user.EnsureWebAuthnHandle(); err != nil || generated || !bytes.Equal(user.WebAuthnHandle, handle) {
+		t.Errorf("existing WebAuthn handle was replaced")
+	}
+
+	//passkeys registered before handles were generated carry the user name
+	legacy := &User{UserName: "legacy", Passkeys: Passkeys{{ID: []byte("one")}}}
+	if generated, err := legacy.EnsureWebAuthnHandle(); err != nil || generated {
+		t.Errorf("WebAuthn handle was generated for an account with passkeys")
+	}
+	if string(legacy.WebAuthnUserHandle()) != legacy.UserName {
+		t.Errorf("incorrect legacy user handle: expected %s but got %s", legacy.UserName, legacy.WebAuthnUserHandle())
+	}
+}
@@ -0,0 +1,130 @@
+package users
+
+//pgMigration is a versioned schema change for the PostgresStore.
+//Migrations are applied in order, each within its own transaction,
+//and the version of each applied migration is recorded in the
+//schema_migrations table so that it is never applied twice.
+type pgMigration struct {
+	version     int
+	description string
+	sql         string
+}
+
+//pgMigrations is the ordered list of schema migrations for the PostgresStore.
+//Never edit or reorder a migration that has been released; always append a new one.
+var pgMigrations = []*pgMigration{
+	{
+		version:     1,
+		description: "create users table",
+		sql: `
+CREATE TABLE users (
+	id BIGSERIAL PRIMARY KEY,
+	user_name TEXT NOT NULL,
+	password_hash BYTEA,
+	personal_name TEXT NOT NULL DEFAULT '',
+	family_name TEXT NOT NULL DEFAULT '',
+	email TEXT NOT NULL DEFAULT '',
+	mobile TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX users_user_name_idx ON users (user_name);
+CREATE INDEX users_email_lower_idx ON users (lower(email));
+`,
+	},
+	{
+		version:     2,
+		description: "add users.version",
+		sql:         `ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	},
+	{
+		version:     3,
+		description: "add users.credentials_changed",
+		sql: `ALTER TABLE users ADD COLUMN credentials_changed TIMESTAMPTZ NOT NULL
+			DEFAULT '0001-01-01 00:00:00+00';`,
+	},
+	{
+		version:     4,
+		description: "add users.email_verified",
+		sql:         `ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false;`,
+	},
+	{
+		version:     5,
+		description: "add users.mobile_verified",
+		sql:         `ALTER TABLE users ADD COLUMN mobile_verified BOOLEAN NOT NULL DEFAULT false;`,
+	},
+	{
+		version:     6,
+		description: "add TOTP columns",
+		sql: `ALTER TABLE users ADD COLUMN totp_secret BYTEA;
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN recovery_codes TEXT[];`,
+	},
+	{
+		version:     7,
+		description: "add users.passkeys",
+		sql:         `ALTER TABLE users ADD COLUMN passkeys JSONB;`,
+	},
+	{
+		version:     8,
+		description: "add users.role and users.disabled",
+		sql: `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false;`,
+	},
+	{
+		version:     9,
+		description: "replace users.disabled with users.status and add users.purge_after",
+		sql: `ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN purge_after TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+UPDATE users SET status = 'suspended' WHERE disabled;
+ALTER TABLE users DROP COLUMN disabled;`,
+	},
+	{
+		version:     10,
+		description: "index users.purge_after for the purger",
+		sql:         `CREATE INDEX users_purge_after_idx ON users (purge_after);`,
+	},
+	{
+		version:     11,
+		description: "create clients table for the clients.PostgresStore",
+		sql: `
+CREATE TABLE clients (
+	id TEXT PRIMARY KEY,
+	secret_hash TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL,
+	redirect_uris TEXT[] NOT NULL,
+	owner TEXT NOT NULL,
+	created TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX clients_owner_idx ON clients (owner);
+`,
+	},
+	{
+		version:     12,
+		description: "create identity_links table for the identities.PostgresStore",
+		sql: `
+CREATE TABLE identity_links (
+	id TEXT PRIMARY KEY,
+	provider TEXT NOT NULL,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_name TEXT NOT NULL,
+	email TEXT NOT NULL DEFAULT '',
+	linked TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX identity_links_user_name_idx ON identity_links (user_name);
+`,
+	},
+	{
+		version:     13,
+		description: "add users.directory",
+		sql:         `ALTER TABLE users ADD COLUMN directory BOOLEAN NOT NULL DEFAULT false;`,
+	},
+	{
+		version:     14,
+		description: "add users.webauthn_handle",
+		sql: `
+ALTER TABLE users ADD COLUMN webauthn_handle BYTEA;
+CREATE UNIQUE INDEX users_webauthn_handle_idx ON users (webauthn_handle);
+`,
+	},
+}
@@ -0,0 +1,332 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//pgMigrationsLockID is the key of the advisory lock held while applying
+//migrations, so that concurrently-starting instances don't race
+const pgMigrationsLockID = 7304827161
+
+//pgUserColumnNames are the columns of the users table that hold User
+//fields, in the same order as the field pointers returned by pgUserFields
+var pgUserColumnNames = []string{
+	"user_name",
+	"password_hash",
+	"personal_name",
+	"family_name",
+	"email",
+	"email_verified",
+	"mobile",
+	"mobile_verified",
+	"credentials_changed",
+	"totp_secret",
+	"totp_enabled",
+	"totp_last_step",
+	"recovery_codes",
+	"passkeys",
+	"role",
+	"status",
+	"purge_after",
+	"directory",
+	"webauthn_handle",
+	"version",
+}
+
+//pgUserColumns is pgUserColumnNames as a comma-delimited list
+var pgUserColumns = strings.Join(pgUserColumnNames, ", ")
+
+//pgUserFields returns pointers to the fields of user that are stored
+//in the columns listed in pgUserColumnNames. These are used as both
+//scan destinations and query arguments.
+func pgUserFields(user *User) []interface{} {
+	return []interface{}{
+		&user.UserName,
+		&user.PasswordHash,
+		&user.PersonalName,
+		&user.FamilyName,
+		&user.Email,
+		&user.EmailVerified,
+		&user.Mobile,
+		&user.MobileVerified,
+		&user.CredentialsChanged,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		pq.Array(&user.RecoveryCodes),
+		&user.Passkeys,
+		&user.Role,
+		&user.Status,
+		&user.PurgeAfter,
+		&user.Directory,
+		&user.WebAuthnHandle,
+		&user.Version,
+	}
+}
+
+//PostgresStore is an implementation of the Store interface for PostgreSQL
+type PostgresStore struct {
+	db *sql.DB
+}
+
+//NewPostgresStore constructs a new PostgresStore, applying any
+//schema migrations that have not yet been applied to the database.
+//The db should be opened using a PostgreSQL driver.
+func NewPostgresStore(db *sql.DB) (*PostgresStore, error) {
+	ps := &PostgresStore{db: db}
+	if err := ps.migrate(); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+//DB returns the underlying database, so that other stores can
+//use the tables created by the PostgresStore's migrations
+func (ps *PostgresStore) DB() *sql.DB {
+	return ps.db
+}
+
+//Get returns the user associated with the provided userName
+func (ps *PostgresStore) Get(userName string) (*User, error) {
+	row := ps.db.QueryRow("SELECT "+pgUserColumns+" FROM users WHERE user_name = $1", userName)
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, unavailable("getting user", err)
+	}
+	return user, nil
+}
+
+//GetByVerifiedEmail returns the user associated with the provided verified email
+func (ps *PostgresStore) GetByVerifiedEmail(email string) (*User, error) {
+	row := ps.db.QueryRow("SELECT "+pgUserColumns+" FROM users WHERE email_verified AND lower(email) = lower($1) ORDER BY id LIMIT 1", email)
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, unavailable("getting user by email", err)
+	}
+	return user, nil
+}
+
+//GetByWebAuthnHandle returns the user associated with the provided WebAuthn user handle
+func (ps *PostgresStore) GetByWebAuthnHandle(handle []byte) (*User, error) {
+	row := ps.db.QueryRow("SELECT "+pgUserColumns+" FROM users WHERE webauthn_handle = $1", handle)
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, unavailable("getting user by WebAuthn handle", err)
+	}
+	return user, nil
+}
+
+//Insert inserts a new user into the store
+func (ps *PostgresStore) Insert(user *User) error {
+	inserting := *user
+	inserting.Version = 1
+	placeholders := make([]string, len(pgUserColumnNames))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	//ON CONFLICT DO NOTHING relies on the unique index on user_name
+	//to make the existence check and insert atomic
+	result, err := ps.db.Exec(fmt.Sprintf("INSERT INTO users (%s) VALUES (%s) ON CONFLICT (user_name) DO NOTHING",
+		pgUserColumns, strings.Join(placeholders, ", ")), pgUserFields(&inserting)...)
+	if err != nil {
+		return unavailable("inserting user", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return unavailable("inserting user", err)
+	}
+	if inserted == 0 {
+		return ErrUserNameTaken
+	}
+	user.Version = 1
+	return nil
+}
+
+//Update updates properties of an existing user
+func (ps *PostgresStore) Update(userName string, updates *Updates, version int) (*User, error) {
+	var sets []string
+	var args []interface{}
+	addSet := func(column string, value *string) {
+		if value != nil {
+			args = append(args, *value)
+			sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	addSet("personal_name", updates.PersonalName)
+	addSet("family_name", updates.FamilyName)
+	addSet("email", updates.Email)
+	addSet("mobile", updates.Mobile)
+	if len(sets) == 0 {
+		return nil, ErrNothingToUpdate
+	}
+	if updates.Email != nil {
+		sets = append(sets, "email_verified = false")
+	}
+	if updates.Mobile != nil {
+		sets = append(sets, "mobile_verified = false")
+	}
+
+	args = append(args, userName, version)
+	row := ps.db.QueryRow(fmt.Sprintf(`UPDATE users SET %s, version = version + 1
+		WHERE user_name = $%d AND ($%d = 0 OR version = $%d) RETURNING %s`,
+		strings.Join(sets, ", "), len(args)-1, len(args), len(args), pgUserColumns), args...)
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ps.notFoundOrMismatch(userName)
+	}
+	if err != nil {
+		return nil, unavailable("updating user", err)
+	}
+	return user, nil
+}
+
+//Save replaces the stored user record
+func (ps *PostgresStore) Save(user *User) error {
+	//user_name is $1 and version is the last argument
+	args := pgUserFields(user)
+	var sets []string
+	for i, column := range pgUserColumnNames {
+		if column != "user_name" && column != "version" {
+			sets = append(sets, fmt.Sprintf("%s = $%d", column, i+1))
+		}
+	}
+	row := ps.db.QueryRow(fmt.Sprintf(`UPDATE users SET %s, version = version + 1
+		WHERE user_name = $1 AND ($%d = 0 OR version = $%d) RETURNING version`,
+		strings.Join(sets, ", "), len(args), len(args)), args...)
+	var version int
+	err := row.Scan(&version)
+	if err == sql.ErrNoRows {
+		return ps.notFoundOrMismatch(user.UserName)
+	}
+	if err != nil {
+		return unavailable("saving user", err)
+	}
+	user.Version = version
+	return nil
+}
+
+//Delete deletes the user
+func (ps *PostgresStore) Delete(userName string, version int) error {
+	result, err := ps.db.Exec("DELETE FROM users WHERE user_name = $1 AND ($2 = 0 OR version = $2)",
+		userName, version)
+	if err != nil {
+		return unavailable("deleting user", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return unavailable("deleting user", err)
+	}
+	if deleted == 0 {
+		return ps.notFoundOrMismatch(userName)
+	}
+	return nil
+}
+
+//ListPurgeable returns the names of up to limit users whose
+//grace periods have passed at now, earliest first
+func (ps *PostgresStore) ListPurgeable(now time.Time, limit int) ([]string, error) {
+	rows, err := ps.db.Query(`SELECT user_name FROM users WHERE purge_after > $1 AND purge_after <= $2
+		ORDER BY purge_after LIMIT $3`, time.Time{}, now, limit)
+	if err != nil {
+		return nil, unavailable("listing purgeable users", err)
+	}
+	defer rows.Close()
+	userNames := []string{}
+	for rows.Next() {
+		var userName string
+		if err := rows.Scan(&userName); err != nil {
+			return nil, unavailable("listing purgeable users", err)
+		}
+		userNames = append(userNames, userName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, unavailable("listing purgeable users", err)
+	}
+	return userNames, nil
+}
+
+//notFoundOrMismatch determines why a conditional write matched no rows:
+//either the user doesn't exist, or its version didn't match
+func (ps *PostgresStore) notFoundOrMismatch(userName string) error {
+	if _, err := ps.Get(userName); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+//migrate applies all migrations in pgMigrations that
+//have not yet been applied to the database
+func (ps *PostgresStore) migrate() error {
+	if _, err := ps.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+	for _, m := range pgMigrations {
+		if err := ps.applyMigration(m); err != nil {
+			return fmt.Errorf("error applying migration %d (%s): %v", m.version, m.description, err)
+		}
+	}
+	return nil
+}
+
+//applyMigration applies m within a transaction if it has not already been applied
+func (ps *PostgresStore) applyMigration(m *pgMigration) error {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", pgMigrationsLockID); err != nil {
+		return err
+	}
+	var applied bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)",
+		m.version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, description) VALUES ($1, $2)",
+		m.version, m.description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+//scanUser scans a row containing pgUserColumns into a new User
+func scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	if err := row.Scan(pgUserFields(user)...); err != nil {
+		return nil, err
+	}
+	user.CredentialsChanged = user.CredentialsChanged.UTC()
+	user.PurgeAfter = user.PurgeAfter.UTC()
+	return user, nil
+}
@@ -0,0 +1,33 @@
+package users
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+//TestPostgresStore runs against the database identified by the
+//POSTGRES_TEST_DSN environment variable, and is skipped if that is not set
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if len(dsn) == 0 {
+		t.Skip("skipping PostgreSQL tests: POSTGRES_TEST_DSN not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewPostgresStore(db)
+	if err != nil {
+		t.Fatalf("error creating PostgresStore: %v", err)
+	}
+	//applying migrations a second time must be a no-op
+	if _, err := NewPostgresStore(db); err != nil {
+		t.Fatalf("error re-applying migrations: %v", err)
+	}
+	testStore(t, store)
+}
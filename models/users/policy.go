@@ -0,0 +1,56 @@
+package users
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+//BreachChecker reports how many times a password was seen in data breaches
+type BreachChecker interface {
+	Count(password string) (int, error)
+}
+
+//PasswordPolicy determines which new passwords are acceptable
+type PasswordPolicy struct {
+	//MinLength is the minimum number of characters
+	MinLength int
+	//MinScore is the minimum zxcvbn strength score, from 0 to 4
+	MinScore int
+	//Breaches, if non-nil, is used to reject passwords that
+	//have appeared in data breaches
+	Breaches BreachChecker
+}
+
+//CurrentPasswordPolicy is the policy applied to passwords at sign-up and
+//whenever a password is changed or reset. This is a var so that it can
+//be configured at startup.
+var CurrentPasswordPolicy = &PasswordPolicy{MinLength: 1, MinScore: 2}
+
+//Validate ensures that password satisfies the policy. The userInputs are
+//other values the user supplied (e.g., email), which are penalized if they
+//appear within the password. If the breach check fails, the error is logged
+//and the password is accepted, so that an unavailable corpus doesn't
+//prevent sign-ups.
+func (p *PasswordPolicy) Validate(password string, userInputs ...string) error {
+	if len(password) == 0 {
+		return fmt.Errorf("password must be supplied")
+	}
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password is too short: it must be at least %d characters", p.MinLength)
+	}
+	passScore := zxcvbn.PasswordStrength(password, userInputs).Score
+	if passScore < p.MinScore {
+		return fmt.Errorf("password is not strong enough: score (%d) must be >= %d", passScore, p.MinScore)
+	}
+	if p.Breaches != nil {
+		count, err := p.Breaches.Count(password)
+		if err != nil {
+			log.Printf("error checking password against breaches: %v", err)
+		} else if count > 0 {
+			return fmt.Errorf("password has appeared in a data breach, so it's not safe to use; please choose another")
+		}
+	}
+	return nil
+}
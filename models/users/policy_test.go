@@ -0,0 +1,48 @@
+package users
+
+import (
+	"errors"
+	"testing"
+)
+
+//fakeBreaches is a BreachChecker backed by a map
+type fakeBreaches struct {
+	counts map[string]int
+	err    error
+}
+
+func (fb *fakeBreaches) Count(password string) (int, error) {
+	return fb.counts[password], fb.err
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength: 12,
+		MinScore:  2,
+		Breaches:  &fakeBreaches{counts: map[string]int{"correct horse battery staple": 3}},
+	}
+	cases := []struct {
+		password string
+		valid    bool
+	}{
+		{"", false},
+		{"Zq8#vL2!", false},
+		{"aaaaaaaaaaaaaaaa", false},
+		{"correct horse battery staple", false},
+		{"purple monkey dishwasher zebra", true},
+	}
+	for _, c := range cases {
+		err := policy.Validate(c.password)
+		if c.valid && err != nil {
+			t.Errorf("'%s' should be valid but got error: %v", c.password, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("'%s' should be invalid", c.password)
+		}
+	}
+
+	policy.Breaches = &fakeBreaches{err: errors.New("corpus unavailable")}
+	if err := policy.Validate("purple monkey dishwasher zebra"); err != nil {
+		t.Errorf("breach check errors should not reject passwords, but got: %v", err)
+	}
+}
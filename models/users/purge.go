@@ -0,0 +1,40 @@
+package users
+
+import (
+	"errors"
+	"time"
+)
+
+//PurgeUser permanently deletes the account of userName if its grace period
+//has passed at now, returning true if it was deleted. It returns false without
+//an error if the account is already gone, was restored, or was rescheduled.
+//Before deleting the account, it calls cleanup to remove everything else that
+//grants access to it, and leaves the account in place if cleanup fails, so
+//that nothing outlives the account and passes to a new owner of its name.
+//Several processes may purge at once, as the account is deleted only at
+//the version that was found to be purgeable.
+func PurgeUser(store Store, userName string, now time.Time, cleanup func(user *User) error) (bool, error) {
+	user, err := store.Get(userName)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !user.Purgeable(now) {
+		return false, nil
+	}
+	if err := cleanup(user); err != nil {
+		return false, err
+	}
+	err = store.Delete(userName, user.Version)
+	//the account was deleted, or changed since it was found to be purgeable,
+	//in which case it's checked again the next time it's listed
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrVersionMismatch) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
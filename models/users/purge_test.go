@@ -0,0 +1,79 @@
+package users
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPurgeUser(t *testing.T) {
+	store := NewMemStore()
+	now := time.Now()
+	insert := func(userName string, grace time.Duration) *User {
+		user := &User{UserName: userName}
+		if err := store.Insert(user); err != nil {
+			t.Fatalf("error inserting %s: %v", userName, err)
+		}
+		user.ScheduleDeletion(grace)
+		if err := store.Save(user); err != nil {
+			t.Fatalf("error saving %s: %v", userName, err)
+		}
+		return user
+	}
+
+	insert("due", -time.Minute)
+	restored := insert("restored", -time.Minute)
+	restored.SetStatus(StatusActive)
+	if err := store.Save(restored); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+	insert("rescheduled", time.Hour)
+	suspended := insert("suspended", -time.Minute)
+	suspended.SetStatus(StatusSuspended)
+	if err := store.Save(suspended); err != nil {
+		t.Fatalf("error suspending user: %v", err)
+	}
+
+	cases := []struct {
+		userName string
+		purged   bool
+	}{
+		{"restored", false},
+		{"rescheduled", false},
+		{"gone", false},
+		{"suspended", true},
+		{"due", true},
+	}
+	cleaned := map[string]bool{}
+	cleanup := func(user *User) error {
+		cleaned[user.UserName] = true
+		return nil
+	}
+	for _, c := range cases {
+		purged, err := PurgeUser(store, c.userName, now, cleanup)
+		if err != nil {
+			t.Errorf("error purging %s: %v", c.userName, err)
+		}
+		if purged != c.purged {
+			t.Errorf("incorrect result purging %s: expected %t but got %t", c.userName, c.purged, purged)
+		}
+		_, err = store.Get(c.userName)
+		if exists := err == nil; c.userName != "gone" && exists == c.purged {
+			t.Errorf("incorrect existence of %s after purging: expected %t but got %t", c.userName, !c.purged, exists)
+		}
+		if cleaned[c.userName] != c.purged {
+			t.Errorf("incorrect cleanup of %s: expected %t but got %t", c.userName, c.purged, cleaned[c.userName])
+		}
+	}
+
+	//accounts whose cleanup fails are kept, to be purged on a later pass
+	insert("uncleaned", -time.Minute)
+	failure := errors.New("cleanup failed")
+	purged, err := PurgeUser(store, "uncleaned", now, func(user *User) error { return failure })
+	if purged || err != failure {
+		t.Errorf("incorrect result when cleanup fails: expected false and %v but got %t and %v", failure, purged, err)
+	}
+	if _, err := store.Get("uncleaned"); err != nil {
+		t.Errorf("account was deleted although cleanup failed: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package users
+
+import (
+	"fmt"
+)
+
+//Role determines what a user may do to the accounts of other users
+type Role string
+
+const (
+	//RoleUser may act only on their own account. Users
+	//stored without a role have this role.
+	RoleUser Role = "user"
+	//RoleSupport may view and edit the profiles of users
+	RoleSupport Role = "support"
+	//RoleAdmin may do anything to any account but their own
+	RoleAdmin Role = "admin"
+)
+
+//roleRanks orders the roles; roles other than RoleAdmin
+//act only on accounts with roles of lower rank
+var roleRanks = map[Role]int{
+	RoleUser:    0,
+	RoleSupport: 1,
+	RoleAdmin:   2,
+}
+
+//Permission is an action on a user account
+type Permission string
+
+const (
+	//PermViewPrivate allows viewing the private fields of a profile
+	PermViewPrivate Permission = "users.view-private"
+	//PermEditProfile allows editing a profile, though only
+	//the account's owner may change its email or mobile
+	PermEditProfile Permission = "users.edit-profile"
+	//PermDeleteAccount allows deleting an account
+	PermDeleteAccount Permission = "users.delete"
+	//PermSetStatus allows suspending and reactivating an account,
+	//including restoring one that is pending deletion
+	PermSetStatus Permission = "users.set-status"
+	//PermAssignRole allows changing the role of an account
+	PermAssignRole Permission = "users.assign-role"
+	//PermRegisterClient allows registering OAuth clients, which may then
+	//be issued tokens for any user who signs in through them
+	PermRegisterClient Permission = "clients.register"
+)
+
+//ownPermissions are the permissions every user has on their own account
+var ownPermissions = []Permission{PermViewPrivate, PermEditProfile, PermDeleteAccount}
+
+//rolePermissions are the permissions each role has on the accounts of others
+var rolePermissions = map[Role][]Permission{
+	RoleUser:    nil,
+	RoleSupport: {PermViewPrivate, PermEditProfile},
+	RoleAdmin:   {PermViewPrivate, PermEditProfile, PermSetStatus, PermAssignRole},
+}
+
+//servicePermissions are the permissions each role has that don't act on an account
+var servicePermissions = map[Role][]Permission{
+	RoleAdmin: {PermRegisterClient},
+}
+
+//ParseRole returns the Role named by s
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, found := roleRanks[role]; !found {
+		return "", fmt.Errorf("unknown role '%s'", s)
+	}
+	return role, nil
+}
+
+//RoleAssignment is the body of a request to change a user's role
+type RoleAssignment struct {
+	Role string `json:"role"`
+}
+
+//EffectiveRole returns the user's role, which is RoleUser if none was assigned
+func (u *User) EffectiveRole() Role {
+	if len(u.Role) == 0 {
+		return RoleUser
+	}
+	return u.Role
+}
+
+//Can returns true if the user may perform perm on the account of target.
+//On their own account, users have only the permissions every user has, so
+//that administrators can't disable themselves or revoke their own role.
+func (u *User) Can(perm Permission, target *User) bool {
+	if target.UserName == u.UserName {
+		return hasPermission(ownPermissions, perm)
+	}
+	role := u.EffectiveRole()
+	if role != RoleAdmin && roleRanks[target.EffectiveRole()] >= roleRanks[role] {
+		return false
+	}
+	return hasPermission(rolePermissions[role], perm)
+}
+
+//Has returns true if the user's role grants perm, which
+//doesn't act on any account, such as PermRegisterClient
+func (u *User) Has(perm Permission) bool {
+	return hasPermission(servicePermissions[u.EffectiveRole()], perm)
+}
+
+//hasPermission returns true if perms contains perm
+func hasPermission(perms []Permission, perm Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package users
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	user := &User{UserName: "user"}
+	otherUser := &User{UserName: "other-user", Role: RoleUser}
+	support := &User{UserName: "support", Role: RoleSupport}
+	otherSupport := &User{UserName: "other-support", Role: RoleSupport}
+	admin := &User{UserName: "admin", Role: RoleAdmin}
+	otherAdmin := &User{UserName: "other-admin", Role: RoleAdmin}
+
+	cases := []struct {
+		actor    *User
+		perm     Permission
+		target   *User
+		expected bool
+	}{
+		{user, PermViewPrivate, user, true},
+		{user, PermEditProfile, user, true},
+		{user, PermDeleteAccount, user, true},
+		{user, PermAssignRole, user, false},
+		{user, PermViewPrivate, otherUser, false},
+		{support, PermViewPrivate, user, true},
+		{support, PermEditProfile, user, true},
+		{support, PermSetStatus, user, false},
+		{support, PermDeleteAccount, user, false},
+		{support, PermViewPrivate, otherSupport, false},
+		{support, PermViewPrivate, admin, false},
+		{admin, PermSetStatus, user, true},
+		{admin, PermAssignRole, support, true},
+		{admin, PermAssignRole, otherAdmin, true},
+		{admin, PermDeleteAccount, user, false},
+		{admin, PermAssignRole, admin, false},
+		{admin, PermSetStatus, admin, false},
+	}
+	for _, c := range cases {
+		if got := c.actor.Can(c.perm, c.target); got != c.expected {
+			t.Errorf("%s %s %s: expected %t but got %t", c.actor.UserName, c.perm, c.target.UserName, c.expected, got)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	cases := []struct {
+		actor    *User
+		perm     Permission
+		expected bool
+	}{
+		{&User{UserName: "user"}, PermRegisterClient, false},
+		{&User{UserName: "support", Role: RoleSupport}, PermRegisterClient, false},
+		{&User{UserName: "admin", Role: RoleAdmin}, PermRegisterClient, true},
+		{&User{UserName: "admin", Role: RoleAdmin}, PermSetStatus, false},
+	}
+	for _, c := range cases {
+		if got := c.actor.Has(c.perm); got != c.expected {
+			t.Errorf("%s %s: expected %t but got %t", c.actor.UserName, c.perm, c.expected, got)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, s := range []string{"user", "support", "admin"} {
+		if role, err := ParseRole(s); err != nil || string(role) != s {
+			t.Errorf("error parsing role %s: %v", s, err)
+		}
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Errorf("expected error parsing unknown role")
+	}
+	if role := (&User{}).EffectiveRole(); role != RoleUser {
+		t.Errorf("incorrect role for user without one: expected %s but got %s", RoleUser, role)
+	}
+}
@@ -0,0 +1,97 @@
+package users
+
+import (
+	"fmt"
+	"time"
+)
+
+//Status is the lifecycle state of an account. Only
+//active accounts may sign in or use their sessions.
+type Status string
+
+const (
+	//StatusActive is the status of accounts in good standing.
+	//Users stored without a status have this status.
+	StatusActive Status = "active"
+	//StatusSuspended is the status of accounts an administrator has suspended
+	StatusSuspended Status = "suspended"
+	//StatusPendingDeletion is the status of accounts their users
+	//have deleted, which may be restored until they are purged
+	StatusPendingDeletion Status = "pending-deletion"
+)
+
+//StatusChange is the body of a request to change an account's status
+type StatusChange struct {
+	Status string `json:"status"`
+}
+
+//ParseStatus returns the Status named by s
+func ParseStatus(s string) (Status, error) {
+	switch status := Status(s); status {
+	case StatusActive, StatusSuspended, StatusPendingDeletion:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown status '%s'", s)
+	}
+}
+
+//legacyStatus is the flag that Status replaced, which Bolt and DynamoDB
+//records stored before then may still have; Postgres migrates it instead
+type legacyStatus struct {
+	//UserName is decoded only because gob refuses to decode
+	//records that have no fields in common with the target
+	UserName string `dynamodbav:"-"`
+	Disabled bool   `dynamodbav:"disabled"`
+}
+
+//apply suspends user if the record was disabled and has no Status of its own
+func (ls *legacyStatus) apply(user *User) {
+	if ls.Disabled && len(user.Status) == 0 {
+		user.Status = StatusSuspended
+	}
+}
+
+//EffectiveStatus returns the user's status, which is StatusActive if none was set
+func (u *User) EffectiveStatus() Status {
+	if len(u.Status) == 0 {
+		return StatusActive
+	}
+	return u.Status
+}
+
+//SetStatus sets the user's status to active or suspended. Activating an
+//account restores it, cancelling any scheduled deletion, but suspending it
+//keeps the schedule, so that it's still purged once its grace period passes.
+//Use ScheduleDeletion to make an account pending deletion.
+//This changes only the in-memory user: use Store.Save to persist the change.
+func (u *User) SetStatus(status Status) error {
+	if status != StatusActive && status != StatusSuspended {
+		return fmt.Errorf("status may be set only to '%s' or '%s'", StatusActive, StatusSuspended)
+	}
+	u.Status = status
+	if status == StatusActive {
+		u.PurgeAfter = time.Time{}
+	}
+	return nil
+}
+
+//DeletionScheduled returns true if the account's deletion has been
+//scheduled and not cancelled, even if it has since been suspended
+func (u *User) DeletionScheduled() bool {
+	return !u.PurgeAfter.IsZero()
+}
+
+//ScheduleDeletion marks the account as pending deletion, to be purged once
+//gracePeriod has passed, and returns the time after which it may be purged.
+//This changes only the in-memory user: use Store.Save to persist the change.
+func (u *User) ScheduleDeletion(gracePeriod time.Duration) time.Time {
+	u.Status = StatusPendingDeletion
+	u.PurgeAfter = time.Now().Add(gracePeriod).UTC().Truncate(time.Microsecond)
+	return u.PurgeAfter
+}
+
+//Purgeable returns true if the account's deletion is scheduled and its
+//grace period has passed at now, so that it may be permanently deleted
+func (u *User) Purgeable(now time.Time) bool {
+	return u.DeletionScheduled() && !u.PurgeAfter.After(now)
+}
@@ -0,0 +1,59 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	user := &User{UserName: "test"}
+	if status := user.EffectiveStatus(); status != StatusActive {
+		t.Errorf("incorrect status for user without one: expected %s but got %s", StatusActive, status)
+	}
+
+	purgeAfter := user.ScheduleDeletion(time.Hour)
+	if user.EffectiveStatus() != StatusPendingDeletion || !user.PurgeAfter.Equal(purgeAfter) {
+		t.Errorf("deletion was not scheduled: %+v", user)
+	}
+	if user.Purgeable(time.Now()) {
+		t.Errorf("user should not be purgeable during the grace period")
+	}
+	if !user.Purgeable(purgeAfter) || !user.Purgeable(purgeAfter.Add(time.Minute)) {
+		t.Errorf("user should be purgeable once the grace period has passed")
+	}
+	if private := user.Private(); private.PurgeAfter == nil || !private.PurgeAfter.Equal(purgeAfter) {
+		t.Errorf("private view should include purgeAfter: %+v", private)
+	}
+
+	if err := user.SetStatus(StatusSuspended); err != nil {
+		t.Fatalf("error suspending user: %v", err)
+	}
+	if !user.DeletionScheduled() || !user.PurgeAfter.Equal(purgeAfter) {
+		t.Errorf("suspending should keep the scheduled deletion: %+v", user)
+	}
+	if !user.Purgeable(purgeAfter) {
+		t.Errorf("suspended user should still be purgeable once the grace period has passed")
+	}
+
+	if err := user.SetStatus(StatusActive); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+	if user.Purgeable(purgeAfter) || !user.PurgeAfter.IsZero() {
+		t.Errorf("restored user should not be purgeable: %+v", user)
+	}
+	if private := user.Private(); private.PurgeAfter != nil {
+		t.Errorf("private view of active user should not include purgeAfter: %+v", private)
+	}
+	if err := user.SetStatus(StatusPendingDeletion); err == nil {
+		t.Errorf("expected error setting status to %s", StatusPendingDeletion)
+	}
+
+	for _, s := range []string{"active", "suspended", "pending-deletion"} {
+		if status, err := ParseStatus(s); err != nil || string(status) != s {
+			t.Errorf("error parsing status %s: %v", s, err)
+		}
+	}
+	if _, err := ParseStatus("disabled"); err == nil {
+		t.Errorf("expected error parsing unknown status")
+	}
+}
@@ -1,9 +1,81 @@
 package users
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//Errors returned by every Store implementation. Callers should test for
+//these using errors.Is(), as stores may wrap them with additional detail.
+var (
+	//ErrNotFound is returned when the requested user does not exist
+	ErrNotFound = errors.New("user not found")
+	//ErrConflict is returned when a write conflicts with an existing user
+	ErrConflict = errors.New("conflict with existing user")
+	//ErrNothingToUpdate is returned from Update when the updates are empty
+	ErrNothingToUpdate = errors.New("nothing to update")
+	//ErrUnavailable is returned when the backing database can't be reached
+	//or fails to process the request
+	ErrUnavailable = errors.New("user store is unavailable")
+	//ErrVersionMismatch is returned from Update or Delete when the stored
+	//user's version doesn't match the version expected by the caller
+	ErrVersionMismatch = errors.New("user has been modified since it was last read")
+)
+
+//AnyVersion may be passed to Update or Delete to skip the version check
+const AnyVersion = 0
+
+//ErrUserNameTaken is returned from Store.Insert when
+//a user with the same userName already exists.
+//It is a kind of ErrConflict.
+var ErrUserNameTaken = fmt.Errorf("%w: user name is already taken", ErrConflict)
+
 //Store describes what a user store can do
 type Store interface {
+	//Get returns the user associated with userName,
+	//or ErrNotFound if there is no such user
 	Get(userName string) (*User, error)
+	//GetByVerifiedEmail returns the first user whose verified email matches
+	//email case-insensitively, or ErrNotFound if there is no such user.
+	//Unverified emails are ignored, as anyone may claim any address.
+	GetByVerifiedEmail(email string) (*User, error)
+	//GetByWebAuthnHandle returns the user whose WebAuthnHandle
+	//matches handle, or ErrNotFound if there is no such user
+	GetByWebAuthnHandle(handle []byte) (*User, error)
+	//Insert inserts a new user with Version 1, returning ErrUserNameTaken
+	//if a user with the same userName already exists
 	Insert(user *User) error
-	Update(userName string, updates *Updates) (*User, error)
-	Delete(userName string) error
+	//Update applies updates to the user associated with userName and
+	//increments its Version, returning ErrNotFound if there is no such user,
+	//ErrNothingToUpdate if updates contains no changes, or ErrVersionMismatch
+	//if version is not AnyVersion and doesn't match the stored Version
+	Update(userName string, updates *Updates, version int) (*User, error)
+	//Save replaces the stored record for user.UserName with user and
+	//increments user.Version, returning ErrNotFound if there is no such user,
+	//or ErrVersionMismatch if user.Version doesn't match the stored Version.
+	//Use this for changes not covered by Updates.
+	Save(user *User) error
+	//Delete deletes the user associated with userName, returning ErrNotFound
+	//if there is no such user, or ErrVersionMismatch if version is not
+	//AnyVersion and doesn't match the stored Version
+	Delete(userName string, version int) error
+	//ListPurgeable returns the names of up to limit users whose deletions
+	//are scheduled and whose grace periods have passed at now
+	ListPurgeable(now time.Time, limit int) ([]string, error)
+}
+
+//checkVersion returns ErrVersionMismatch if the user's Version
+//does not match the expected version
+func checkVersion(user *User, version int) error {
+	if version != AnyVersion && user.Version != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+//unavailable wraps an error returned by a store's backing
+//database so that it matches ErrUnavailable
+func unavailable(action string, err error) error {
+	return fmt.Errorf("%w: error %s: %v", ErrUnavailable, action, err)
 }
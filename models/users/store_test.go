@@ -0,0 +1,247 @@
+package users
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//testStore runs the conformance tests that every Store implementation must pass
+func testStore(t *testing.T, store Store) {
+	suffix := time.Now().UnixNano()
+	userName := fmt.Sprintf("test-%d", suffix)
+	user := &User{
+		UserName:     userName,
+		PasswordHash: []byte("not-a-real-hash"),
+		PersonalName: "Tester",
+		FamilyName:   "Account",
+		Email:        "test-" + userName + "@test.com",
+		Mobile:       "206-555-1212",
+	}
+
+	if _, err := store.Get(user.UserName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when getting user that does not exist: expected %v but got %v", ErrNotFound, err)
+	}
+	if _, err := store.Update(user.UserName, &Updates{FamilyName: aws.String("UPDATED")}, AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when updating user that does not exist: expected %v but got %v", ErrNotFound, err)
+	}
+	if err := store.Delete(user.UserName, AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when deleting user that does not exist: expected %v but got %v", ErrNotFound, err)
+	}
+
+	if err := store.Insert(user); err != nil {
+		t.Errorf("error inserting new user: %v", err)
+	}
+	if user.Version != 1 {
+		t.Errorf("incorrect version after insert: expected 1 but got %d", user.Version)
+	}
+
+	if _, err := store.GetByVerifiedEmail(user.Email); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when getting user by unverified email: expected %v but got %v", ErrNotFound, err)
+	}
+	if _, err := store.GetByVerifiedEmail("nobody-" + userName + "@test.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when getting user by unknown email: expected %v but got %v", ErrNotFound, err)
+	}
+
+	duplicate := &User{UserName: userName, PersonalName: "Imposter"}
+	if err := store.Insert(duplicate); !errors.Is(err, ErrUserNameTaken) {
+		t.Errorf("incorrect error when inserting duplicate user: expected %v but got %v", ErrUserNameTaken, err)
+	}
+
+	gotUser, err := store.Get(userName)
+	if err != nil {
+		t.Errorf("error getting previously inserted user %s: %v", userName, err)
+	} else {
+		if !reflect.DeepEqual(gotUser, user) {
+			t.Errorf("fetched user does not match inserted user: expected %+v but got %+v", user, gotUser)
+		}
+	}
+
+	if _, err := store.Update(userName, &Updates{}, AnyVersion); !errors.Is(err, ErrNothingToUpdate) {
+		t.Errorf("incorrect error when updating with no updates: expected %v but got %v", ErrNothingToUpdate, err)
+	}
+
+	updates := &Updates{
+		FamilyName: aws.String("UPDATED"),
+	}
+	updatedUser, err := store.Update(userName, updates, user.Version)
+	if err != nil {
+		t.Fatalf("error updating user %s: %v", userName, err)
+	} else {
+		if updatedUser.FamilyName != "UPDATED" {
+			t.Errorf("returned user did not have updates applied: expected familyName='UPDATED' but got familyName='%s'",
+				updatedUser.FamilyName)
+		}
+		if updatedUser.Version != user.Version+1 {
+			t.Errorf("version was not incremented by update: expected %d but got %d", user.Version+1, updatedUser.Version)
+		}
+		if updatedUser.PersonalName != user.PersonalName {
+			t.Errorf("update changed a field that was not updated: expected personalName='%s' but got personalName='%s'",
+				user.PersonalName, updatedUser.PersonalName)
+		}
+	}
+
+	verified := &User{}
+	*verified = *updatedUser
+	verified.EmailVerified = true
+	if err := store.Save(verified); err != nil {
+		t.Fatalf("error saving verified user %s: %v", userName, err)
+	}
+	gotUser, err = store.GetByVerifiedEmail("TEST-" + userName + "@test.com")
+	if err != nil {
+		t.Errorf("error getting user by verified email: %v", err)
+	} else if gotUser.UserName != userName {
+		t.Errorf("incorrect user returned by verified email: expected %s but got %s", userName, gotUser.UserName)
+	}
+	updatedUser, err = store.Update(userName, &Updates{Email: aws.String("changed-" + user.Email)}, verified.Version)
+	if err != nil {
+		t.Fatalf("error updating email of user %s: %v", userName, err)
+	} else if updatedUser.EmailVerified {
+		t.Errorf("updating email did not reset emailVerified")
+	}
+
+	gotUser, err = store.Get(userName)
+	if err != nil {
+		t.Errorf("error getting updated user %s: %v", userName, err)
+	} else if gotUser == nil || gotUser.FamilyName != "UPDATED" {
+		t.Errorf("updates were not persisted: got %+v", gotUser)
+	}
+
+	//the version read before the update is now stale
+	if _, err := store.Update(userName, updates, user.Version); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("incorrect error when updating with stale version: expected %v but got %v", ErrVersionMismatch, err)
+	}
+	if err := store.Delete(userName, user.Version); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("incorrect error when deleting with stale version: expected %v but got %v", ErrVersionMismatch, err)
+	}
+
+	if err := store.Save(&User{UserName: "does-not-exist"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when saving user that does not exist: expected %v but got %v", ErrNotFound, err)
+	}
+	saved := &User{}
+	*saved = *updatedUser
+	saved.PasswordHash = []byte("new-hash")
+	saved.TOTPSecret = []byte("sealed-secret")
+	saved.TOTPEnabled = true
+	saved.TOTPLastStep = 12345
+	saved.RecoveryCodes = []string{"hash-1", "hash-2"}
+	saved.Passkeys = Passkeys{{
+		ID:        []byte("credential-id"),
+		Name:      "laptop",
+		PublicKey: []byte("public-key"),
+		SignCount: 3,
+		Created:   time.Now().UTC().Truncate(time.Microsecond),
+	}}
+	saved.Role = RoleAdmin
+	saved.Status = StatusPendingDeletion
+	saved.PurgeAfter = time.Now().UTC().Truncate(time.Microsecond)
+	saved.Directory = true
+	saved.WebAuthnHandle = []byte("handle-" + userName)
+	if err := store.Save(saved); err != nil {
+		t.Errorf("error saving user %s: %v", userName, err)
+	} else if saved.Version != updatedUser.Version+1 {
+		t.Errorf("version was not incremented by save: expected %d but got %d", updatedUser.Version+1, saved.Version)
+	}
+	gotUser, err = store.Get(userName)
+	if err != nil {
+		t.Errorf("error getting saved user %s: %v", userName, err)
+	} else if !reflect.DeepEqual(gotUser, saved) {
+		t.Errorf("fetched user does not match saved user: expected %+v but got %+v", saved, gotUser)
+	}
+	gotUser, err = store.GetByWebAuthnHandle(saved.WebAuthnHandle)
+	if err != nil {
+		t.Errorf("error getting user by WebAuthn handle: %v", err)
+	} else if gotUser.UserName != userName {
+		t.Errorf("incorrect user returned by WebAuthn handle: expected %s but got %s", userName, gotUser.UserName)
+	}
+	if _, err := store.GetByWebAuthnHandle([]byte("unknown-" + userName)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when getting user by unknown WebAuthn handle: expected %v but got %v", ErrNotFound, err)
+	}
+	listed := func(now time.Time) bool {
+		userNames, err := store.ListPurgeable(now, 1000)
+		if err != nil {
+			t.Errorf("error listing purgeable users: %v", err)
+		}
+		for _, name := range userNames {
+			if name == userName {
+				return true
+			}
+		}
+		return false
+	}
+	if !listed(saved.PurgeAfter) {
+		t.Errorf("user %s was not listed as purgeable once its grace period passed", userName)
+	}
+	if listed(saved.PurgeAfter.Add(-time.Second)) {
+		t.Errorf("user %s was listed as purgeable during its grace period", userName)
+	}
+	stale := &User{}
+	*stale = *user
+	if err := store.Save(stale); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("incorrect error when saving with stale version: expected %v but got %v", ErrVersionMismatch, err)
+	}
+
+	if err := store.Delete(userName, saved.Version); err != nil {
+		t.Errorf("error deleting user %s: %v", userName, err)
+	}
+
+	if _, err := store.Get(userName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("incorrect error when getting deleted user: expected %v but got %v", ErrNotFound, err)
+	}
+}
+
+func TestLegacyDisabled(t *testing.T) {
+	//records stored before Status existed had a disabled flag instead
+	type legacyUser struct {
+		UserName string
+		Email    string
+		Disabled bool
+	}
+	cases := []struct {
+		disabled bool
+		expected Status
+	}{
+		{true, StatusSuspended},
+		{false, ""},
+	}
+	for _, c := range cases {
+		buf := &bytes.Buffer{}
+		if err := gob.NewEncoder(buf).Encode(&legacyUser{UserName: "legacy", Email: "legacy@test.com", Disabled: c.disabled}); err != nil {
+			t.Fatalf("error encoding legacy record: %v", err)
+		}
+		user, err := decodeGobUser(buf.Bytes())
+		if err != nil {
+			t.Fatalf("error decoding legacy gob record: %v", err)
+		}
+		if user.Status != c.expected || user.Email != "legacy@test.com" {
+			t.Errorf("incorrect gob user for disabled=%t: expected status '%s' but got %+v", c.disabled, c.expected, user)
+		}
+
+		item := map[string]*dynamodb.AttributeValue{
+			"userName": {S: aws.String("legacy")},
+			"email":    {S: aws.String("legacy@test.com")},
+			"disabled": {BOOL: aws.Bool(c.disabled)},
+		}
+		user, err = decodeDynamoUser(item)
+		if err != nil {
+			t.Fatalf("error decoding legacy DynamoDB item: %v", err)
+		}
+		if user.Status != c.expected || user.Email != "legacy@test.com" {
+			t.Errorf("incorrect DynamoDB user for disabled=%t: expected status '%s' but got %+v", c.disabled, c.expected, user)
+		}
+	}
+
+	//records with a status of their own keep it
+	buf := &bytes.Buffer{}
+	gob.NewEncoder(buf).Encode(&User{UserName: "current", Status: StatusPendingDeletion})
+	if user, err := decodeGobUser(buf.Bytes()); err != nil || user.Status != StatusPendingDeletion {
+		t.Errorf("incorrect status for current record: expected %s but got %+v (%v)", StatusPendingDeletion, user, err)
+	}
+}
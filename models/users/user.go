@@ -1,16 +1,19 @@
 package users
 
 import (
+	"errors"
 	"fmt"
 	"net/mail"
+	"time"
 
-	"github.com/nbutton23/zxcvbn-go"
+	"github.com/davestearns/userservice/passhash"
 	"golang.org/x/crypto/bcrypt"
 )
 
-//bcryptCost is the cost factor used when generating bcrypt password hashes.
-//This is a var and not a const so that automated tests can set it to the minimum.
-var bcryptCost = bcrypt.DefaultCost
+//PasswordHasher is used to generate new password hashes. Existing hashes
+//produced by a weaker hasher are upgraded on sign-in. This is a var so
+//that it can be configured at startup, and set to a fast hasher in tests.
+var PasswordHasher passhash.Hasher = &passhash.Bcrypt{Cost: bcrypt.DefaultCost}
 
 //NewUser represents a new user being added to the system
 type NewUser struct {
@@ -33,19 +36,15 @@ type NewUser struct {
 	Mobile string `json:"mobile"`
 }
 
-//Validate validates the NewUser
+//Validate validates the NewUser, normalizing Mobile to E.164 format
 func (nu *NewUser) Validate() error {
 	//UserName must be non-zero-length
 	if len(nu.UserName) == 0 {
 		return fmt.Errorf("userName must be supplied")
 	}
-	//password must be complex enough
-	if len(nu.Password) == 0 {
-		return fmt.Errorf("password must be supplied")
-	}
-	passScore := zxcvbn.PasswordStrength(nu.Password, []string{nu.Email}).Score
-	if passScore < 2 {
-		return fmt.Errorf("password is not strong enough: score (%d) must be >= 2", passScore)
+	//password must satisfy the password policy
+	if err := CurrentPasswordPolicy.Validate(nu.Password, nu.Email); err != nil {
+		return err
 	}
 	//email must be valid if provided
 	if len(nu.Email) > 0 {
@@ -53,6 +52,14 @@ func (nu *NewUser) Validate() error {
 			return fmt.Errorf("invalid email address: %v", err)
 		}
 	}
+	//mobile must be valid if provided
+	if len(nu.Mobile) > 0 {
+		mobile, err := NormalizeMobile(nu.Mobile)
+		if err != nil {
+			return err
+		}
+		nu.Mobile = mobile
+	}
 	return nil
 }
 
@@ -61,16 +68,20 @@ func (nu *NewUser) ToUser() (*User, error) {
 	if err := nu.Validate(); err != nil {
 		return nil, err
 	}
-	passhash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcryptCost)
+	hash, err := PasswordHasher.Hash([]byte(nu.Password))
 	if err != nil {
 		return nil, fmt.Errorf("error generating password hash: %v", err)
 	}
 	return &User{
 		UserName:     nu.UserName,
 		Email:        nu.Email,
-		PasswordHash: passhash,
+		Mobile:       nu.Mobile,
+		PasswordHash: hash,
 		PersonalName: nu.PersonalName,
 		FamilyName:   nu.FamilyName,
+		//the name may have belonged to a purged account, whose
+		//sessions and tokens must not be valid for this one
+		CredentialsChanged: time.Now().UTC().Truncate(time.Microsecond),
 	}, nil
 }
 
@@ -82,11 +93,133 @@ type User struct {
 	FamilyName   string `json:"familyName,omitempty"`
 	Email        string `json:"-" dynamodbav:"email,omitempty"`
 	Mobile       string `json:"-" dynamodbav:"mobile,omitempty"`
+	//EmailVerified is true once the user has proven they control Email.
+	//It is reset to false whenever Email is updated.
+	EmailVerified bool `json:"-" dynamodbav:"emailVerified"`
+	//MobileVerified is true once the user has proven they control Mobile.
+	//It is reset to false whenever Mobile is updated.
+	MobileVerified bool `json:"-" dynamodbav:"mobileVerified"`
+	//CredentialsChanged is when the user's credentials were last changed.
+	//Sessions that began before this time are no longer valid.
+	CredentialsChanged time.Time `json:"-" dynamodbav:"credentialsChanged"`
+	//TOTPSecret is the user's TOTP secret, sealed with the server's MFA keys
+	TOTPSecret []byte `json:"-" dynamodbav:"totpSecret,omitempty"`
+	//TOTPEnabled is true once the user has confirmed TOTP enrollment,
+	//after which sign-in requires a TOTP or recovery code
+	TOTPEnabled bool `json:"-" dynamodbav:"totpEnabled"`
+	//TOTPLastStep is the time step of the last TOTP code accepted,
+	//so that each code can be used only once
+	TOTPLastStep int64 `json:"-" dynamodbav:"totpLastStep"`
+	//RecoveryCodes are hashes of the unused one-time recovery codes
+	RecoveryCodes []string `json:"-" dynamodbav:"recoveryCodes,omitempty"`
+	//Passkeys are the WebAuthn credentials the user has registered
+	Passkeys Passkeys `json:"-" dynamodbav:"passkeys,omitempty"`
+	//Role determines what the user may do to the accounts of other users
+	Role Role `json:"-" dynamodbav:"role,omitempty"`
+	//Status is the lifecycle state of the account
+	Status Status `json:"-" dynamodbav:"status,omitempty"`
+	//PurgeAfter is when an account whose deletion is scheduled
+	//may be permanently deleted, which is kept if it's suspended
+	PurgeAfter time.Time `json:"-" dynamodbav:"purgeAfter"`
+	//Directory is true if the account was provisioned from an LDAP
+	//directory, which alone may then sign the user in and update the
+	//name and email; passkeys, linked identities and refresh tokens
+	//are refused for such accounts
+	Directory bool `json:"-" dynamodbav:"directory,omitempty"`
+	//WebAuthnHandle is the random WebAuthn user handle stored in the
+	//user's passkeys, which identifies the user when they sign in with one
+	WebAuthnHandle []byte `json:"-" dynamodbav:"webAuthnHandle,omitempty"`
+	//Version is incremented by the Store each time the user is updated
+	Version int `json:"-" dynamodbav:"version"`
+}
+
+//PrivateUser is the view of a user returned only to that user,
+//which includes contact details that are hidden from other users
+type PrivateUser struct {
+	UserName       string `json:"userName"`
+	PersonalName   string `json:"personalName,omitempty"`
+	FamilyName     string `json:"familyName,omitempty"`
+	Email          string `json:"email,omitempty"`
+	EmailVerified  bool   `json:"emailVerified"`
+	Mobile         string `json:"mobile,omitempty"`
+	MobileVerified bool   `json:"mobileVerified"`
+	MFAEnabled     bool   `json:"mfaEnabled"`
+	Role           Role   `json:"role"`
+	Status         Status `json:"status"`
+	//PurgeAfter is set only for accounts whose deletion is scheduled
+	PurgeAfter *time.Time `json:"purgeAfter,omitempty"`
+}
+
+//Private returns the private view of the user
+func (u *User) Private() *PrivateUser {
+	pu := &PrivateUser{
+		UserName:       u.UserName,
+		PersonalName:   u.PersonalName,
+		FamilyName:     u.FamilyName,
+		Email:          u.Email,
+		EmailVerified:  u.EmailVerified,
+		Mobile:         u.Mobile,
+		MobileVerified: u.MobileVerified,
+		MFAEnabled:     u.TOTPEnabled,
+		Role:           u.EffectiveRole(),
+		Status:         u.EffectiveStatus(),
+	}
+	if u.DeletionScheduled() {
+		purgeAfter := u.PurgeAfter
+		pu.PurgeAfter = &purgeAfter
+	}
+	return pu
 }
 
 //Authenticate authenticates the user using the provided password
 func (u *User) Authenticate(password []byte) error {
-	return bcrypt.CompareHashAndPassword(u.PasswordHash, password)
+	return passhash.Verify(u.PasswordHash, password)
+}
+
+//RehashPassword rehashes the already-authenticated password using
+//PasswordHasher if the stored hash was produced by a weaker algorithm or
+//parameters, and returns true if it did. This changes only the in-memory
+//user: use Store.Save to persist the change.
+func (u *User) RehashPassword(password []byte) (bool, error) {
+	if !PasswordHasher.NeedsRehash(u.PasswordHash) {
+		return false, nil
+	}
+	hash, err := PasswordHasher.Hash(password)
+	if err != nil {
+		return false, fmt.Errorf("error generating password hash: %v", err)
+	}
+	u.PasswordHash = hash
+	return true, nil
+}
+
+//ErrInvalidPassword is returned from ChangePassword
+//when the current password is incorrect
+var ErrInvalidPassword = errors.New("current password is incorrect")
+
+//ChangePassword changes the user's password to newPassword, provided that
+//currentPassword is correct and newPassword satisfies CurrentPasswordPolicy.
+//This changes only the in-memory user: use Store.Save to persist the change.
+func (u *User) ChangePassword(currentPassword string, newPassword string) error {
+	if err := u.Authenticate([]byte(currentPassword)); err != nil {
+		return ErrInvalidPassword
+	}
+	return u.SetPassword(newPassword)
+}
+
+//SetPassword sets the user's password to newPassword without checking the
+//current password, provided that newPassword satisfies CurrentPasswordPolicy.
+//This changes only the in-memory user: use Store.Save to persist the change.
+func (u *User) SetPassword(newPassword string) error {
+	if err := CurrentPasswordPolicy.Validate(newPassword, u.Email); err != nil {
+		return err
+	}
+	hash, err := PasswordHasher.Hash([]byte(newPassword))
+	if err != nil {
+		return fmt.Errorf("error generating password hash: %v", err)
+	}
+	u.PasswordHash = hash
+	u.CredentialsChanged = time.Now().UTC().Truncate(time.Microsecond)
+	return nil
 }
 
 //DummyAuthenticate consumes about the same amount of time
@@ -95,7 +228,7 @@ func (u *User) Authenticate(password []byte) error {
 //so that an attacker can't see a difference in response time
 //between an invalid userName and a valid userName with invalid password.
 func DummyAuthenticate() {
-	bcrypt.GenerateFromPassword([]byte("dummy password"), bcryptCost)
+	PasswordHasher.Hash([]byte("dummy password"))
 }
 
 //Updates represents updates to a user profile sent by the client.
@@ -107,8 +240,67 @@ type Updates struct {
 	Mobile       *string `json:"mobile,omitempty"`
 }
 
+//Validate validates the Updates, normalizing Mobile to E.164 format
+func (up *Updates) Validate() error {
+	if up.Email != nil && len(*up.Email) > 0 {
+		if _, err := mail.ParseAddress(*up.Email); err != nil {
+			return fmt.Errorf("invalid email address: %v", err)
+		}
+	}
+	if up.Mobile != nil && len(*up.Mobile) > 0 {
+		mobile, err := NormalizeMobile(*up.Mobile)
+		if err != nil {
+			return err
+		}
+		up.Mobile = &mobile
+	}
+	return nil
+}
+
+//applyUpdates applies updates to the user in memory
+func (u *User) applyUpdates(updates *Updates) error {
+	if updates.PersonalName == nil && updates.FamilyName == nil &&
+		updates.Email == nil && updates.Mobile == nil {
+		return ErrNothingToUpdate
+	}
+	if updates.PersonalName != nil {
+		u.PersonalName = *updates.PersonalName
+	}
+	if updates.FamilyName != nil {
+		u.FamilyName = *updates.FamilyName
+	}
+	if updates.Email != nil {
+		u.Email = *updates.Email
+		u.EmailVerified = false
+	}
+	if updates.Mobile != nil {
+		u.Mobile = *updates.Mobile
+		u.MobileVerified = false
+	}
+	return nil
+}
+
+//PasswordChange represents a request to change the user's password
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+//Validate validates the PasswordChange
+func (pc *PasswordChange) Validate() error {
+	if len(pc.CurrentPassword) == 0 {
+		return fmt.Errorf("currentPassword must be supplied")
+	}
+	if len(pc.NewPassword) == 0 {
+		return fmt.Errorf("newPassword must be supplied")
+	}
+	return nil
+}
+
 //Credentials represents a user's sign-in credentials
 type Credentials struct {
 	UserName string `json:"userName,omitempty"`
 	Password string `json:"password,omitempty"`
+	//RememberMe selects a longer-lived session
+	RememberMe bool `json:"rememberMe,omitempty"`
 }
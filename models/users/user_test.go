@@ -0,0 +1,97 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/davestearns/userservice/passhash"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestChangePassword(t *testing.T) {
+	PasswordHasher = &passhash.Bcrypt{Cost: bcrypt.MinCost}
+	nu := &NewUser{UserName: "tester", Password: "correct horse battery staple", Email: "test@test.com"}
+	user, err := nu.ToUser()
+	if err != nil {
+		t.Fatalf("error converting new user: %v", err)
+	}
+	//a reused name must not inherit the tokens of a purged account
+	created := user.CredentialsChanged
+	if created.IsZero() {
+		t.Errorf("CredentialsChanged was not set when the user was created")
+	}
+
+	if err := user.ChangePassword("wrong password", "new unguessable passphrase"); err != ErrInvalidPassword {
+		t.Errorf("incorrect error with wrong current password: expected %v but got %v", ErrInvalidPassword, err)
+	}
+	if err := user.ChangePassword(nu.Password, "password"); err == nil {
+		t.Errorf("did not receive expected error when changing to a weak password")
+	}
+	if !user.CredentialsChanged.Equal(created) {
+		t.Errorf("failed password changes should not update CredentialsChanged")
+	}
+
+	if err := user.ChangePassword(nu.Password, "new unguessable passphrase"); err != nil {
+		t.Fatalf("error changing password: %v", err)
+	}
+	if err := user.Authenticate([]byte("new unguessable passphrase")); err != nil {
+		t.Errorf("new password did not authenticate: %v", err)
+	}
+	if err := user.Authenticate([]byte(nu.Password)); err == nil {
+		t.Errorf("old password still authenticates after change")
+	}
+	if !user.CredentialsChanged.After(created) {
+		t.Errorf("CredentialsChanged was not updated")
+	}
+}
+
+func TestRehashPassword(t *testing.T) {
+	PasswordHasher = &passhash.Bcrypt{Cost: bcrypt.MinCost}
+	nu := &NewUser{UserName: "tester", Password: "correct horse battery staple"}
+	user, err := nu.ToUser()
+	if err != nil {
+		t.Fatalf("error converting new user: %v", err)
+	}
+	created := user.CredentialsChanged
+	if rehashed, err := user.RehashPassword([]byte(nu.Password)); err != nil || rehashed {
+		t.Errorf("hash from the current hasher should not be rehashed: %v, %v", rehashed, err)
+	}
+
+	PasswordHasher = &passhash.Argon2id{Memory: 1024, Time: 1, Threads: 1, KeyLength: 32}
+	defer func() { PasswordHasher = &passhash.Bcrypt{Cost: bcrypt.MinCost} }()
+	rehashed, err := user.RehashPassword([]byte(nu.Password))
+	if err != nil || !rehashed {
+		t.Fatalf("bcrypt hash was not upgraded to argon2id: %v, %v", rehashed, err)
+	}
+	if err := user.Authenticate([]byte(nu.Password)); err != nil {
+		t.Errorf("password did not authenticate after rehash: %v", err)
+	}
+	if !user.CredentialsChanged.Equal(created) {
+		t.Errorf("rehashing should not update CredentialsChanged")
+	}
+}
+
+func TestNewExternalUser(t *testing.T) {
+	u, err := NewExternalUser("tester", "Test", "User", "test@example.com", true)
+	if err != nil {
+		t.Fatalf("error constructing external user: %v", err)
+	}
+	if u.HasPassword() {
+		t.Errorf("external user must not have a password")
+	}
+	if !u.EmailVerified {
+		t.Errorf("email verified by the provider must be verified")
+	}
+	if u.CredentialsChanged.IsZero() {
+		t.Errorf("CredentialsChanged was not set when the user was created")
+	}
+	if err := u.Authenticate([]byte("")); err == nil {
+		t.Errorf("external user authenticated with an empty password")
+	}
+
+	if _, err := NewExternalUser("", "", "", "", false); err == nil {
+		t.Errorf("expected an error for a missing userName")
+	}
+	if _, err := NewExternalUser("tester", "", "", "not-an-email", true); err == nil {
+		t.Errorf("expected an error for an invalid email")
+	}
+}
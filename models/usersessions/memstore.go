@@ -0,0 +1,81 @@
+package usersessions
+
+import (
+	"sort"
+	"sync"
+)
+
+//MemStore is an in-memory implementation of the Store interface,
+//suitable for automated tests and local development
+type MemStore struct {
+	mx    sync.RWMutex
+	users map[string]map[string]*Session
+}
+
+//NewMemStore constructs a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		users: map[string]map[string]*Session{},
+	}
+}
+
+//Add adds a session to the user's index
+func (ms *MemStore) Add(userName string, session *Session) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	sessions, found := ms.users[userName]
+	if !found {
+		sessions = map[string]*Session{}
+		ms.users[userName] = sessions
+	}
+	stored := *session
+	sessions[session.ID] = &stored
+	return nil
+}
+
+//List returns all sessions in the user's index, oldest first
+func (ms *MemStore) List(userName string) ([]*Session, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	list := []*Session{}
+	for _, s := range ms.users[userName] {
+		stored := *s
+		list = append(list, &stored)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Began.Before(list[j].Began) })
+	return list, nil
+}
+
+//Get returns the session in the user's index with the given public ID
+func (ms *MemStore) Get(userName string, id string) (*Session, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	s, found := ms.users[userName][id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	stored := *s
+	return &stored, nil
+}
+
+//Remove removes the session with the given public ID from the user's index
+func (ms *MemStore) Remove(userName string, id string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, found := ms.users[userName][id]; !found {
+		return ErrNotFound
+	}
+	delete(ms.users[userName], id)
+	if len(ms.users[userName]) == 0 {
+		delete(ms.users, userName)
+	}
+	return nil
+}
+
+//RemoveAll removes the user's entire index
+func (ms *MemStore) RemoveAll(userName string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	delete(ms.users, userName)
+	return nil
+}
@@ -0,0 +1,53 @@
+package usersessions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+	now := time.Now()
+	older := NewSession("sid-1", now.Add(-time.Hour), "10.0.0.1:1234", "laptop")
+	newer := NewSession("sid-2", now, "10.0.0.2:1234", "phone")
+	if older.ID == older.SessionID {
+		t.Fatalf("public ID must not equal the session ID")
+	}
+	store.Add("tester", newer)
+	store.Add("tester", older)
+	store.Add("other", NewSession("sid-3", now, "10.0.0.3:1234", "tablet"))
+
+	list, err := store.List("tester")
+	if err != nil {
+		t.Fatalf("error listing sessions: %v", err)
+	}
+	if len(list) != 2 || list[0].SessionID != "sid-1" || list[1].SessionID != "sid-2" {
+		t.Errorf("incorrect sessions listed: %+v", list)
+	}
+	got, err := store.Get("tester", PublicID("sid-2"))
+	if err != nil {
+		t.Fatalf("error getting session: %v", err)
+	}
+	if got.UserAgent != "phone" {
+		t.Errorf("incorrect session returned: %+v", got)
+	}
+	if _, err := store.Get("other", PublicID("sid-2")); err != ErrNotFound {
+		t.Errorf("incorrect error getting another user's session: expected %v but got %v", ErrNotFound, err)
+	}
+
+	if err := store.Remove("tester", older.ID); err != nil {
+		t.Errorf("error removing session: %v", err)
+	}
+	if err := store.Remove("tester", older.ID); err != ErrNotFound {
+		t.Errorf("incorrect error removing session twice: expected %v but got %v", ErrNotFound, err)
+	}
+	if err := store.RemoveAll("tester"); err != nil {
+		t.Errorf("error removing all sessions: %v", err)
+	}
+	if list, _ := store.List("tester"); len(list) != 0 {
+		t.Errorf("sessions remained after RemoveAll: %+v", list)
+	}
+	if list, _ := store.List("other"); len(list) != 1 {
+		t.Errorf("RemoveAll removed another user's sessions")
+	}
+}
@@ -0,0 +1,110 @@
+package usersessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//redisKeyPrefix is prepended to user names to form redis keys
+const redisKeyPrefix = "usersessions:"
+
+//RedisStore is an implementation of the Store interface for redis.
+//Each user's index is a redis hash from public ID to JSON-encoded
+//Session, which expires once none of its sessions could still be valid.
+type RedisStore struct {
+	pool *redis.Pool
+	ttl  time.Duration
+}
+
+//NewRedisStore constructs a new RedisStore using the provided connection
+//pool. The ttl should be the maximum lifetime of a session.
+func NewRedisStore(pool *redis.Pool, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+		ttl:  ttl,
+	}
+}
+
+//Add adds a session to the user's index
+func (rs *RedisStore) Add(userName string, session *Session) error {
+	j, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("error encoding session: %v", err)
+	}
+	key := redisKeyPrefix + userName
+	conn := rs.pool.Get()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("HSET", key, session.ID, j)
+	conn.Send("PEXPIRE", key, rs.ttl.Nanoseconds()/int64(time.Millisecond))
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("error adding session: %v", err)
+	}
+	return nil
+}
+
+//List returns all sessions in the user's index, oldest first
+func (rs *RedisStore) List(userName string) ([]*Session, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	vals, err := redis.ByteSlices(conn.Do("HVALS", redisKeyPrefix+userName))
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %v", err)
+	}
+	list := []*Session{}
+	for _, j := range vals {
+		s := &Session{}
+		if err := json.Unmarshal(j, s); err != nil {
+			return nil, fmt.Errorf("error decoding session: %v", err)
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Began.Before(list[j].Began) })
+	return list, nil
+}
+
+//Get returns the session in the user's index with the given public ID
+func (rs *RedisStore) Get(userName string, id string) (*Session, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	j, err := redis.Bytes(conn.Do("HGET", redisKeyPrefix+userName, id))
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting session: %v", err)
+	}
+	s := &Session{}
+	if err := json.Unmarshal(j, s); err != nil {
+		return nil, fmt.Errorf("error decoding session: %v", err)
+	}
+	return s, nil
+}
+
+//Remove removes the session with the given public ID from the user's index
+func (rs *RedisStore) Remove(userName string, id string) error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	removed, err := redis.Int(conn.Do("HDEL", redisKeyPrefix+userName, id))
+	if err != nil {
+		return fmt.Errorf("error removing session: %v", err)
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+//RemoveAll removes the user's entire index
+func (rs *RedisStore) RemoveAll(userName string) error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", redisKeyPrefix+userName); err != nil {
+		return fmt.Errorf("error removing sessions: %v", err)
+	}
+	return nil
+}
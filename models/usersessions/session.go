@@ -0,0 +1,39 @@
+//Package usersessions maintains an index of each user's sessions,
+//so that users can see and revoke their sessions on other devices
+package usersessions
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+//Session describes one of a user's sessions
+type Session struct {
+	//ID is a public identifier for the session, derived from SessionID.
+	//It's safe to expose to clients, unlike SessionID, which is the
+	//bearer credential for the session.
+	ID string `json:"id"`
+	//SessionID is the ID of the session in the session store
+	SessionID    string    `json:"sessionID"`
+	Began        time.Time `json:"began"`
+	ClientIPPath string    `json:"clientIPPath"`
+	UserAgent    string    `json:"userAgent"`
+}
+
+//NewSession constructs a new Session for sessionID
+func NewSession(sessionID string, began time.Time, clientIPPath string, userAgent string) *Session {
+	return &Session{
+		ID:           PublicID(sessionID),
+		SessionID:    sessionID,
+		Began:        began,
+		ClientIPPath: clientIPPath,
+		UserAgent:    userAgent,
+	}
+}
+
+//PublicID returns the public identifier for sessionID
+func PublicID(sessionID string) string {
+	hash := sha256.Sum256([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(hash[:16])
+}
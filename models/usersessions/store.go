@@ -0,0 +1,20 @@
+package usersessions
+
+import "errors"
+
+//ErrNotFound is returned when the user has no session with a given ID
+var ErrNotFound = errors.New("session not found")
+
+//Store describes what a session index store can do
+type Store interface {
+	//Add adds a session to the user's index
+	Add(userName string, session *Session) error
+	//List returns all sessions in the user's index
+	List(userName string) ([]*Session, error)
+	//Get returns the session in the user's index with the given public ID
+	Get(userName string, id string) (*Session, error)
+	//Remove removes the session with the given public ID from the user's index
+	Remove(userName string, id string) error
+	//RemoveAll removes the user's entire index
+	RemoveAll(userName string) error
+}
@@ -0,0 +1,104 @@
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+//argon2SaltLength is the number of random bytes in each salt
+const argon2SaltLength = 16
+
+//Argon2id is a Hasher using argon2id
+type Argon2id struct {
+	//Memory is the amount of memory used, in KiB
+	Memory uint32
+	//Time is the number of passes over the memory
+	Time uint32
+	//Threads is the degree of parallelism
+	Threads uint8
+	//KeyLength is the length of the hash in bytes
+	KeyLength uint32
+}
+
+//argon2Params are the parameters and outputs decoded from an encoded hash
+type argon2Params struct {
+	Argon2id
+	salt []byte
+	key  []byte
+}
+
+//Hash returns the encoded hash of password
+func (a *Argon2id) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+	key := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLength)
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+//NeedsRehash returns true if encoded is from a weaker algorithm,
+//or is an argon2id hash with any weaker parameter
+func (a *Argon2id) NeedsRehash(encoded []byte) bool {
+	switch algorithm(encoded) {
+	case algorithmBcrypt:
+		return true
+	case algorithmArgon2id:
+		params, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false
+		}
+		return params.Memory < a.Memory || params.Time < a.Time ||
+			params.Threads < a.Threads || params.KeyLength < a.KeyLength
+	default:
+		return false
+	}
+}
+
+//verifyArgon2id verifies password against an argon2id hash
+func verifyArgon2id(encoded []byte, password []byte) error {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey(password, params.salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+//decodeArgon2id decodes a PHC-encoded argon2id hash
+func decodeArgon2id(encoded []byte) (*argon2Params, error) {
+	//"$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>" splits into
+	//an empty string followed by five parts
+	parts := strings.Split(string(encoded), "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version '%s'", parts[2])
+	}
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %v", err)
+	}
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %v", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: %v", err)
+	}
+	params.KeyLength = uint32(len(params.key))
+	return params, nil
+}
@@ -0,0 +1,40 @@
+package passhash
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//Bcrypt is a Hasher using bcrypt
+type Bcrypt struct {
+	Cost int
+}
+
+//Hash returns the encoded hash of password
+func (b *Bcrypt) Hash(password []byte) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword(password, b.Cost)
+	if err != nil {
+		return nil, fmt.Errorf("error generating bcrypt hash: %v", err)
+	}
+	return hash, nil
+}
+
+//NeedsRehash returns true if encoded is a bcrypt hash with a lower cost.
+//Hashes from stronger algorithms are not downgraded.
+func (b *Bcrypt) NeedsRehash(encoded []byte) bool {
+	if algorithm(encoded) != algorithmBcrypt {
+		return false
+	}
+	cost, err := bcrypt.Cost(encoded)
+	return err == nil && cost < b.Cost
+}
+
+//verifyBcrypt verifies password against a bcrypt hash
+func verifyBcrypt(encoded []byte, password []byte) error {
+	err := bcrypt.CompareHashAndPassword(encoded, password)
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrMismatch
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+//Package passhash hashes and verifies passwords using pluggable
+//algorithms. Hashes are encoded in the PHC string format
+//($<id>$<params>$<salt>$<hash>) so that the algorithm and parameters
+//used for each hash are stored along with it.
+package passhash
+
+import (
+	"errors"
+	"strings"
+)
+
+//ErrMismatch is returned from Verify when the password doesn't match the hash
+var ErrMismatch = errors.New("password does not match")
+
+//ErrUnknownAlgorithm is returned from Verify when the hash
+//was produced by an algorithm this package doesn't support
+var ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+
+//Hasher produces password hashes using a particular algorithm and parameters
+type Hasher interface {
+	//Hash returns the encoded hash of password
+	Hash(password []byte) ([]byte, error)
+	//NeedsRehash returns true if encoded was produced by a weaker
+	//algorithm, or by this algorithm with weaker parameters
+	NeedsRehash(encoded []byte) bool
+}
+
+//Verify verifies password against an encoded hash produced by any
+//supported Hasher, returning ErrMismatch if it doesn't match
+func Verify(encoded []byte, password []byte) error {
+	switch algorithm(encoded) {
+	case algorithmBcrypt:
+		return verifyBcrypt(encoded, password)
+	case algorithmArgon2id:
+		return verifyArgon2id(encoded, password)
+	default:
+		return ErrUnknownAlgorithm
+	}
+}
+
+const (
+	algorithmBcrypt   = "bcrypt"
+	algorithmArgon2id = "argon2id"
+)
+
+//algorithm returns the algorithm that produced the encoded hash.
+//bcrypt hashes use their own modular crypt format ($2a$, $2b$, etc.),
+//which is also what was stored before hashes were PHC-encoded.
+func algorithm(encoded []byte) string {
+	s := string(encoded)
+	switch {
+	case strings.HasPrefix(s, "$2"):
+		return algorithmBcrypt
+	case strings.HasPrefix(s, "$argon2id$"):
+		return algorithmArgon2id
+	default:
+		return ""
+	}
+}
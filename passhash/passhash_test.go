@@ -0,0 +1,61 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashers(t *testing.T) {
+	hashers := map[string]Hasher{
+		"bcrypt":   &Bcrypt{Cost: bcrypt.MinCost},
+		"argon2id": &Argon2id{Memory: 1024, Time: 1, Threads: 1, KeyLength: 32},
+	}
+	for name, hasher := range hashers {
+		hash, err := hasher.Hash([]byte("correct horse"))
+		if err != nil {
+			t.Fatalf("%s: error hashing: %v", name, err)
+		}
+		if err := Verify(hash, []byte("correct horse")); err != nil {
+			t.Errorf("%s: error verifying correct password: %v", name, err)
+		}
+		if err := Verify(hash, []byte("wrong horse")); err != ErrMismatch {
+			t.Errorf("%s: incorrect error verifying wrong password: expected %v but got %v", name, ErrMismatch, err)
+		}
+		if hasher.NeedsRehash(hash) {
+			t.Errorf("%s: hash should not need rehashing by the hasher that produced it", name)
+		}
+	}
+	if err := Verify([]byte("$unknown$hash"), []byte("password")); err != ErrUnknownAlgorithm {
+		t.Errorf("incorrect error verifying unknown algorithm: expected %v but got %v", ErrUnknownAlgorithm, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weakBcrypt, _ := (&Bcrypt{Cost: bcrypt.MinCost}).Hash([]byte("password"))
+	strongBcrypt, _ := (&Bcrypt{Cost: bcrypt.MinCost + 1}).Hash([]byte("password"))
+	weakArgon, _ := (&Argon2id{Memory: 1024, Time: 1, Threads: 1, KeyLength: 32}).Hash([]byte("password"))
+	if !strings.HasPrefix(string(weakArgon), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("incorrect PHC encoding: %s", weakArgon)
+	}
+
+	bcryptHasher := &Bcrypt{Cost: bcrypt.MinCost + 1}
+	if !bcryptHasher.NeedsRehash(weakBcrypt) {
+		t.Errorf("bcrypt hash with lower cost should need rehashing")
+	}
+	if bcryptHasher.NeedsRehash(strongBcrypt) {
+		t.Errorf("bcrypt hash with same cost should not need rehashing")
+	}
+	if bcryptHasher.NeedsRehash(weakArgon) {
+		t.Errorf("argon2id hash should not be downgraded to bcrypt")
+	}
+
+	argonHasher := &Argon2id{Memory: 2048, Time: 1, Threads: 1, KeyLength: 32}
+	if !argonHasher.NeedsRehash(strongBcrypt) {
+		t.Errorf("bcrypt hash should be upgraded to argon2id")
+	}
+	if !argonHasher.NeedsRehash(weakArgon) {
+		t.Errorf("argon2id hash with less memory should need rehashing")
+	}
+}
@@ -0,0 +1,67 @@
+//Package sealing encrypts and authenticates small secrets for storage
+package sealing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+//ErrCannotOpen is returned from Open when the sealed data can't
+//be decrypted with any of the keys, or has been tampered with
+var ErrCannotOpen = errors.New("unable to open sealed data")
+
+//Sealer encrypts data using AES-256-GCM
+type Sealer struct {
+	aeads []cipher.AEAD
+}
+
+//NewSealer constructs a new Sealer. Each key is stretched to an AES-256 key
+//using SHA-256. The first key is used to seal new data; all keys are tried
+//when opening, so that keys can be rotated without losing access to data.
+func NewSealer(keys []string) (*Sealer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one key must be supplied")
+	}
+	s := &Sealer{}
+	for _, k := range keys {
+		key := sha256.Sum256([]byte(k))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, fmt.Errorf("error creating cipher: %v", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("error creating GCM cipher: %v", err)
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s, nil
+}
+
+//Seal encrypts plaintext, returning the nonce followed by the ciphertext
+func (s *Sealer) Seal(plaintext []byte) ([]byte, error) {
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+//Open decrypts data previously returned from Seal
+func (s *Sealer) Open(sealed []byte) ([]byte, error) {
+	for _, aead := range s.aeads {
+		if len(sealed) < aead.NonceSize() {
+			return nil, ErrCannotOpen
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrCannotOpen
+}
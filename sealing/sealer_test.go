@@ -0,0 +1,41 @@
+package sealing
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealer(t *testing.T) {
+	old, err := NewSealer([]string{"old-key"})
+	if err != nil {
+		t.Fatalf("error constructing sealer: %v", err)
+	}
+	sealed, err := old.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("error sealing: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Errorf("sealed data contains the plaintext")
+	}
+
+	rotated, err := NewSealer([]string{"new-key", "old-key"})
+	if err != nil {
+		t.Fatalf("error constructing sealer: %v", err)
+	}
+	opened, err := rotated.Open(sealed)
+	if err != nil {
+		t.Fatalf("error opening data sealed with a previous key: %v", err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("incorrect plaintext: expected secret but got %s", opened)
+	}
+
+	other, _ := NewSealer([]string{"other-key"})
+	if _, err := other.Open(sealed); err != ErrCannotOpen {
+		t.Errorf("incorrect error opening with the wrong key: expected %v but got %v", ErrCannotOpen, err)
+	}
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := rotated.Open(sealed); err != ErrCannotOpen {
+		t.Errorf("incorrect error opening tampered data: expected %v but got %v", ErrCannotOpen, err)
+	}
+}
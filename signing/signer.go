@@ -0,0 +1,113 @@
+//Package signing creates and verifies tamper-proof tokens
+//that carry claims and an expiration time
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//ErrInvalidToken is returned from Verify when the token is malformed
+//or its signature doesn't match any of the keys
+var ErrInvalidToken = errors.New("invalid token")
+
+//ErrExpiredToken is returned from Verify when the token has expired
+var ErrExpiredToken = errors.New("token has expired")
+
+//envelope is the signed payload of a token
+type envelope struct {
+	Expires int64           `json:"exp"`
+	Claims  json.RawMessage `json:"claims"`
+}
+
+//Signer signs and verifies tokens using HMAC-SHA256. Tokens are bound to
+//the Signer's purpose, so a token issued for one purpose can't be used
+//for another, even if the same keys are used.
+type Signer struct {
+	purpose string
+	keys    [][]byte
+}
+
+//NewSigner constructs a new Signer for purpose. The first key is used
+//to sign new tokens; all keys are used to verify tokens, so that keys
+//can be rotated without invalidating existing tokens.
+func NewSigner(purpose string, keys []string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one signing key must be supplied")
+	}
+	s := &Signer{purpose: purpose}
+	for _, k := range keys {
+		s.keys = append(s.keys, []byte(k))
+	}
+	return s, nil
+}
+
+//Sign returns a new token containing claims that expires after ttl
+func (s *Signer) Sign(claims interface{}, ttl time.Duration) (string, error) {
+	cj, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("error encoding claims: %v", err)
+	}
+	ej, err := json.Marshal(&envelope{
+		Expires: time.Now().Add(ttl).Unix(),
+		Claims:  cj,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding token: %v", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(ej)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(s.keys[0], payload)), nil
+}
+
+//Verify verifies the token and decodes its claims into the claims parameter
+func (s *Signer) Verify(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	valid := false
+	for _, k := range s.keys {
+		if hmac.Equal(sig, s.mac(k, parts[0])) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidToken
+	}
+
+	ej, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	env := &envelope{}
+	if err := json.Unmarshal(ej, env); err != nil {
+		return ErrInvalidToken
+	}
+	if time.Now().Unix() > env.Expires {
+		return ErrExpiredToken
+	}
+	if err := json.Unmarshal(env.Claims, claims); err != nil {
+		return fmt.Errorf("error decoding claims: %v", err)
+	}
+	return nil
+}
+
+//mac returns the HMAC of the purpose and payload using key
+func (s *Signer) mac(key []byte, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s.purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
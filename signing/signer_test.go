@@ -0,0 +1,62 @@
+package signing
+
+import (
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	UserName string `json:"userName"`
+}
+
+func TestSigner(t *testing.T) {
+	oldSigner, err := NewSigner("test", []string{"old-key"})
+	if err != nil {
+		t.Fatalf("error constructing signer: %v", err)
+	}
+	signer, err := NewSigner("test", []string{"new-key", "old-key"})
+	if err != nil {
+		t.Fatalf("error constructing signer: %v", err)
+	}
+
+	token, err := signer.Sign(&testClaims{UserName: "tester"}, time.Hour)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	claims := &testClaims{}
+	if err := signer.Verify(token, claims); err != nil {
+		t.Fatalf("error verifying token: %v", err)
+	}
+	if claims.UserName != "tester" {
+		t.Errorf("incorrect claims: expected userName=tester but got %s", claims.UserName)
+	}
+
+	//tokens signed with an older key must still verify
+	oldToken, _ := oldSigner.Sign(&testClaims{UserName: "tester"}, time.Hour)
+	if err := signer.Verify(oldToken, &testClaims{}); err != nil {
+		t.Errorf("error verifying token signed with older key: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"tampered", "x" + token},
+	}
+	for _, c := range cases {
+		if err := signer.Verify(c.token, &testClaims{}); err != ErrInvalidToken {
+			t.Errorf("case %s: expected %v but got %v", c.name, ErrInvalidToken, err)
+		}
+	}
+
+	otherPurpose, _ := NewSigner("other", []string{"new-key"})
+	if err := otherPurpose.Verify(token, &testClaims{}); err != ErrInvalidToken {
+		t.Errorf("token verified for a different purpose: expected %v but got %v", ErrInvalidToken, err)
+	}
+
+	expired, _ := signer.Sign(&testClaims{UserName: "tester"}, -time.Minute)
+	if err := signer.Verify(expired, &testClaims{}); err != ErrExpiredToken {
+		t.Errorf("incorrect error verifying expired token: expected %v but got %v", ErrExpiredToken, err)
+	}
+}
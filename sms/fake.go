@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+//Message is a text message sent by a FakeSender
+type Message struct {
+	To   string
+	Body string
+}
+
+//FakeSender is an implementation of the Sender interface that writes
+//messages to an io.Writer (e.g., os.Stdout) instead of sending them.
+//It also retains sent messages so that automated tests can inspect them.
+type FakeSender struct {
+	mx   sync.Mutex
+	w    io.Writer
+	sent []*Message
+}
+
+//NewFakeSender constructs a new FakeSender that writes messages to w.
+//If w is nil, messages are only retained.
+func NewFakeSender(w io.Writer) *FakeSender {
+	return &FakeSender{
+		w: w,
+	}
+}
+
+//Send writes the message
+func (fs *FakeSender) Send(to string, body string) error {
+	fs.mx.Lock()
+	defer fs.mx.Unlock()
+	if fs.w != nil {
+		if _, err := fmt.Fprintf(fs.w, "SMS to %s: %s\n", to, body); err != nil {
+			return fmt.Errorf("error writing SMS: %v", err)
+		}
+	}
+	fs.sent = append(fs.sent, &Message{To: to, Body: body})
+	return nil
+}
+
+//Sent returns all messages sent so far
+func (fs *FakeSender) Sent() []*Message {
+	fs.mx.Lock()
+	defer fs.mx.Unlock()
+	return append([]*Message(nil), fs.sent...)
+}
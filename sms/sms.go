@@ -0,0 +1,8 @@
+//Package sms sends text messages to mobile numbers
+package sms
+
+//Sender describes what an SMS sender can do
+type Sender interface {
+	//Send sends body to the mobile number, which must be in E.164 format
+	Send(to string, body string) error
+}
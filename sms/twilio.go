@@ -0,0 +1,60 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//twilioAPIBase is the base URL of the Twilio REST API
+const twilioAPIBase = "https://api.twilio.com/2010-04-01/Accounts/"
+
+//TwilioSender is an implementation of the Sender interface
+//that sends messages using the Twilio REST API
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+//NewTwilioSender constructs a new TwilioSender that sends
+//messages from the Twilio phone number in from
+func NewTwilioSender(accountSID string, authToken string, from string) *TwilioSender {
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+//Send sends the message
+func (ts *TwilioSender) Send(to string, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", ts.from)
+	form.Set("Body", body)
+	req, err := http.NewRequest(http.MethodPost, twilioAPIBase+url.PathEscape(ts.accountSID)+"/Messages.json",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating SMS request: %v", err)
+	}
+	req.SetBasicAuth(ts.accountSID, ts.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending SMS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error sending SMS: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+//Package totp implements RFC 6238 time-based one-time passwords,
+//as used by authenticator apps
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	//Digits is the number of digits in each code
+	Digits = 6
+	//Period is the number of seconds each code is valid
+	Period = 30
+	//secretLength is the number of random bytes in a new secret
+	secretLength = 20
+)
+
+//encoding is the base32 encoding used for secrets, which is what
+//authenticator apps expect
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+//NewSecret generates a new random secret, encoded in base32
+func NewSecret() (string, error) {
+	buf := make([]byte, secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating random secret: %v", err)
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+//Step returns the time step containing t
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+//Code returns the code for the base32-encoded secret at the time step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %v", err)
+	}
+	return code(key, step, Digits), nil
+}
+
+//Validate checks code against the secret at time t, allowing for one step of
+//clock drift in either direction. If valid, it returns the matching step, which
+//callers should record so that the same code can't be used twice.
+func Validate(secret string, code string, t time.Time) (int64, bool) {
+	current := Step(t)
+	for step := current - 1; step <= current+1; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+//URI returns the otpauth:// URI for the secret, which authenticator
+//apps can import (typically by scanning it as a QR code)
+func URI(issuer string, accountName string, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", Digits))
+	params.Set("period", fmt.Sprintf("%d", Period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+//code implements the HOTP algorithm from RFC 4226
+func code(key []byte, counter int64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	h := hmac.New(sha1.New, key)
+	h.Write(msg)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
@@ -0,0 +1,49 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+//rfcKey is the SHA1 key used by the test vectors in RFC 6238 appendix B
+var rfcKey = []byte("12345678901234567890")
+
+func TestCodeRFCVectors(t *testing.T) {
+	cases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, c := range cases {
+		if got := code(rfcKey, c.unix/Period, 8); got != c.expected {
+			t.Errorf("time %d: expected %s but got %s", c.unix, c.expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+	now := time.Now()
+	current, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+	if step, ok := Validate(secret, current, now); !ok || step != Step(now) {
+		t.Errorf("current code did not validate")
+	}
+	if _, ok := Validate(secret, current, now.Add(Period*time.Second)); !ok {
+		t.Errorf("code from the previous step should validate to allow for clock drift")
+	}
+	if _, ok := Validate(secret, current, now.Add(3*Period*time.Second)); ok {
+		t.Errorf("code from three steps ago should not validate")
+	}
+}